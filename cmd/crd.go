@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/matt-tyler/elasticsearch-operator/pkg/apis/es"
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const crdName = esV1.ResourcePlural + "." + es.GroupName
+
+// NewCustomResourceDefinition returns the definition of the Cluster resource
+func NewCustomResourceDefinition() *apiextensionsv1beta1.CustomResourceDefinition {
+	labelSelectorPath := ".status.selector"
+	return &apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: crdName,
+		},
+		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+			Group:   es.GroupName,
+			Version: esV1.SchemeGroupVersion.Version,
+			Scope:   apiextensionsv1beta1.NamespaceScoped,
+			Names: apiextensionsv1beta1.CustomResourceDefinitionNames{
+				Plural: esV1.ResourcePlural,
+				Kind:   reflect.TypeOf(esV1.Cluster{}).Name(),
+			},
+			Validation: clusterValidation(),
+			Subresources: &apiextensionsv1beta1.CustomResourceSubresources{
+				Status: &apiextensionsv1beta1.CustomResourceSubresourceStatus{},
+				// scaling a cluster scales its data nodes
+				Scale: &apiextensionsv1beta1.CustomResourceSubresourceScale{
+					SpecReplicasPath:   ".spec.size",
+					StatusReplicasPath: ".status.replicas",
+					LabelSelectorPath:  &labelSelectorPath,
+				},
+			},
+			AdditionalPrinterColumns: []apiextensionsv1beta1.CustomResourceColumnDefinition{{
+				Name:     "Phase",
+				Type:     "string",
+				JSONPath: ".status.phase",
+			}, {
+				Name:     "Health",
+				Type:     "string",
+				JSONPath: ".status.health",
+			}, {
+				Name:     "Version",
+				Type:     "string",
+				JSONPath: ".spec.version",
+			}, {
+				Name:     "Age",
+				Type:     "date",
+				JSONPath: ".metadata.creationTimestamp",
+			}},
+		},
+	}
+}
+
+// clusterValidation returns the schema generated from the Cluster type by
+// hack/openapi-gen
+func clusterValidation() *apiextensionsv1beta1.CustomResourceValidation {
+	schema := &apiextensionsv1beta1.JSONSchemaProps{}
+	if err := json.Unmarshal([]byte(esV1.ClusterOpenAPISchema), schema); err != nil {
+		log.NewLogger().Panicf("Invalid Cluster schema: %v", err)
+	}
+	return &apiextensionsv1beta1.CustomResourceValidation{OpenAPIV3Schema: schema}
+}
+
+// InstallCustomResourceDefinition creates the Cluster resource, or patches
+// the spec of an existing definition in place, and waits for it to be
+// established. The versions and conversion registered with the conversion
+// webhook are kept, see crdPatch.
+func InstallCustomResourceDefinition(clientset apiextensionsclient.Interface) (*apiextensionsv1beta1.CustomResourceDefinition, error) {
+	logger := log.NewLogger()
+	desired := NewCustomResourceDefinition()
+	crds := clientset.ApiextensionsV1beta1().CustomResourceDefinitions()
+
+	crd, err := crds.Get(desired.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		logger.Debugf("Creating custom resource:\n%v", PrettyJson(desired))
+		if crd, err = crds.Create(desired); err != nil {
+			logger.Debugf("Failed to create custom resource")
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		logger.Debugf("Updating custom resource:\n%v", PrettyJson(desired))
+		patch, err := crdPatch(crd, desired)
+		if err != nil {
+			return nil, err
+		}
+		if crd, err = crds.Patch(crd.Name, types.MergePatchType, patch); err != nil {
+			logger.Debugf("Failed to update custom resource")
+			return nil, err
+		}
+	}
+
+	err = wait.Poll(500*time.Millisecond, 60*time.Second, func() (bool, error) {
+		crd, err = crds.Get(crdName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, cond := range crd.Status.Conditions {
+			switch cond.Type {
+			case apiextensionsv1beta1.Established:
+				if cond.Status == apiextensionsv1beta1.ConditionTrue {
+					return true, err
+				}
+			case apiextensionsv1beta1.NamesAccepted:
+				if cond.Status == apiextensionsv1beta1.ConditionFalse {
+					logger.Errorf("Name conflict: %v\n", cond.Reason)
+				}
+			}
+		}
+		return false, err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return crd, nil
+}
+
+// crdPatch returns a merge patch setting the spec of live to that of desired.
+// The vendored types predate per-version schemas and conversion, so an
+// update would drop those registered with the conversion webhook. Fields
+// missing from the patch are kept, and once live serves more than one
+// version its versions and schemas are left to webhook.RegisterConversion.
+func crdPatch(live, desired *apiextensionsv1beta1.CustomResourceDefinition) ([]byte, error) {
+	data, err := json.Marshal(desired.Spec)
+	if err != nil {
+		return nil, err
+	}
+	spec := map[string]interface{}{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	if len(live.Spec.Versions) > 1 {
+		delete(spec, "version")
+		delete(spec, "versions")
+		delete(spec, "validation")
+	}
+	return json.Marshal(map[string]interface{}{"spec": spec})
+}
+
+func PrettyJson(v interface{}) string {
+	logger := log.NewLogger()
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		logger.Panicf("%v", err)
+	}
+	return string(b)
+}
+
+var installCRDsCmd = &cobra.Command{
+	Use:   "install-crds",
+	Short: "Install or upgrade the custom resource definitions used by the operator",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := log.NewLogger()
+
+		clientConfig, err := buildConfig(viper.GetString("kubeconfig"))
+		if err != nil {
+			return err
+		}
+
+		clientset, err := apiextensionsclient.NewForConfig(clientConfig)
+		if err != nil {
+			return err
+		}
+
+		if _, err := InstallCustomResourceDefinition(clientset); err != nil {
+			return err
+		}
+		logger.Infof("Custom resource definition %s installed", crdName)
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(installCRDsCmd)
+}
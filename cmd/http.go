@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// check reports the state of the operator, failing with an error
+type check func() (string, error)
+
+// checkHandler responds with the message of check, or with 503 when it fails
+func checkHandler(check check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg, err := check()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, msg)
+	})
+}
+
+// serveHTTP serves the metrics and health checks of the operator on address
+// until the server is closed
+func serveHTTP(address string, healthz, readyz check) *http.Server {
+	logger := log.NewLogger()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/healthz", checkHandler(healthz))
+	mux.Handle("/readyz", checkHandler(readyz))
+
+	server := &http.Server{Addr: address, Handler: mux}
+	go func() {
+		logger.Infof("Serving metrics and health checks on %s", address)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("Failed to serve metrics: %v", err)
+		}
+	}()
+	return server
+}
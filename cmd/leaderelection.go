@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	leaderElectionLockName = "elasticsearch-operator"
+	namespaceFile          = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// leaderElectionConfig holds the leader election flags
+type leaderElectionConfig struct {
+	namespace     string
+	identity      string
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+}
+
+// operatorNamespace returns namespace, defaulting to the namespace the
+// operator runs in
+func operatorNamespace(namespace string) string {
+	if namespace != "" {
+		return namespace
+	}
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace
+	}
+	if b, err := ioutil.ReadFile(namespaceFile); err == nil {
+		return strings.TrimSpace(string(b))
+	}
+	return metav1.NamespaceDefault
+}
+
+// leaderElectionIdentity returns the identity of this replica, defaulting to
+// its hostname which is the name of its pod
+func leaderElectionIdentity(identity string) (string, error) {
+	if identity != "" {
+		return identity, nil
+	}
+	return os.Hostname()
+}
+
+// releasableLock is the lock of the elector, which can be released on
+// shutdown. The elector cannot be stopped, so once released the lock refuses
+// its writes and it can neither renew nor take the lock again.
+type releasableLock struct {
+	resourcelock.Interface
+
+	mu       sync.Mutex
+	released bool
+}
+
+var errLockReleased = errors.New("leader election lock has been released")
+
+func newLock(clientset kubernetes.Interface, config leaderElectionConfig, recorder record.EventRecorder) (*releasableLock, error) {
+	lock, err := resourcelock.New(resourcelock.ConfigMapsResourceLock, config.namespace, leaderElectionLockName, clientset.CoreV1(), resourcelock.ResourceLockConfig{
+		Identity:      config.identity,
+		EventRecorder: recorder,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &releasableLock{Interface: lock}, nil
+}
+
+func (l *releasableLock) Create(record resourcelock.LeaderElectionRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return errLockReleased
+	}
+	return l.Interface.Create(record)
+}
+
+func (l *releasableLock) Update(record resourcelock.LeaderElectionRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return errLockReleased
+	}
+	return l.Interface.Update(record)
+}
+
+func (l *releasableLock) isReleased() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.released
+}
+
+// release stops the elector writing the lock, then gives up the lock if this
+// replica holds it, so another replica can take over without waiting for the
+// lease to expire. A renewal in flight completes first.
+func (l *releasableLock) release() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.released = true
+
+	record, err := l.Interface.Get()
+	if err != nil {
+		return err
+	}
+	if record.HolderIdentity != l.Identity() {
+		return nil
+	}
+
+	record.HolderIdentity = ""
+	record.LeaseDurationSeconds = 1
+	record.RenewTime = metav1.Now()
+	return l.Interface.Update(*record)
+}
+
+// newLeaderElector returns an elector calling run once this replica holds the
+// lock, and the lock to release on shutdown. Losing the lock after starting
+// to lead is fatal unless it was released, as the informers and workqueue of
+// the controller cannot be restarted.
+func newLeaderElector(clientset kubernetes.Interface, config leaderElectionConfig, run func(stop <-chan struct{})) (*leaderelection.LeaderElector, *releasableLock, error) {
+	logger := log.NewLogger()
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: leaderElectionLockName})
+
+	lock, err := newLock(clientset, config, recorder)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: config.leaseDuration,
+		RenewDeadline: config.renewDeadline,
+		RetryPeriod:   config.retryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(stop <-chan struct{}) {
+				logger.Infof("Acquired leadership of %s/%s as %s", config.namespace, leaderElectionLockName, config.identity)
+				run(stop)
+			},
+			OnStoppedLeading: func() {
+				if lock.isReleased() {
+					logger.Infof("Released leadership of %s/%s", config.namespace, leaderElectionLockName)
+					return
+				}
+				logger.Errorf("Lost leadership of %s/%s", config.namespace, leaderElectionLockName)
+				os.Exit(1)
+			},
+			OnNewLeader: func(identity string) {
+				if identity != config.identity {
+					logger.Infof("Waiting for leader %s", identity)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return elector, lock, nil
+}
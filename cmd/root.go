@@ -2,29 +2,24 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
-	"reflect"
 	"syscall"
 	"time"
 
-	"github.com/matt-tyler/elasticsearch-operator/pkg/apis/es"
-	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
 	. "github.com/matt-tyler/elasticsearch-operator/pkg/controller"
 	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/errors"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection"
 )
 
 func buildConfig(kubeconfig string) (*rest.Config, error) {
@@ -34,79 +29,14 @@ func buildConfig(kubeconfig string) (*rest.Config, error) {
 	return rest.InClusterConfig()
 }
 
-const crdName = esV1.ResourcePlural + "." + es.GroupName
-
-func CreateCustomResourceDefinition(clientset apiextensionsclient.Interface) (*apiextensionsv1beta1.CustomResourceDefinition, error) {
-	logger := log.NewLogger()
-	crd := &apiextensionsv1beta1.CustomResourceDefinition{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: crdName,
-		},
-		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
-			Group:   es.GroupName,
-			Version: esV1.SchemeGroupVersion.Version,
-			Scope:   apiextensionsv1beta1.NamespaceScoped,
-			Names: apiextensionsv1beta1.CustomResourceDefinitionNames{
-				Plural: esV1.ResourcePlural,
-				Kind:   reflect.TypeOf(esV1.Cluster{}).Name(),
-			},
-			Subresources: &apiextensionsv1beta1.CustomResourceSubresources{
-				Status: &apiextensionsv1beta1.CustomResourceSubresourceStatus{},
-			},
-		},
-	}
-
-	logger.Debugf("Creating custom resource:\n%v", PrettyJson(crd))
-
-	_, err := clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Create(crd)
-	if err != nil {
-		logger.Debugf("Failed to create custom resource")
-		return nil, err
-	}
-
-	err = wait.Poll(500*time.Millisecond, 60*time.Second, func() (bool, error) {
-		crd, err = clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Get(crdName, metav1.GetOptions{})
-		if err != nil {
-			return false, err
-		}
-		for _, cond := range crd.Status.Conditions {
-			switch cond.Type {
-			case apiextensionsv1beta1.Established:
-				if cond.Status == apiextensionsv1beta1.ConditionTrue {
-					return true, err
-				}
-			case apiextensionsv1beta1.NamesAccepted:
-				if cond.Status == apiextensionsv1beta1.ConditionFalse {
-					logger.Errorf("Name conflict: %v\n", cond.Reason)
-				}
-			}
-		}
-		return false, err
-	})
-
-	if err != nil {
-		deleteErr := clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Delete(crdName, nil)
-		if deleteErr != nil {
-			return nil, errors.NewAggregate([]error{err, deleteErr})
-		}
-		return nil, err
-	}
-	return crd, nil
-}
-
-func PrettyJson(v interface{}) string {
-	logger := log.NewLogger()
-	b, err := json.MarshalIndent(v, "", "  ")
-	if err != nil {
-		logger.Panicf("%v", err)
-	}
-	return string(b)
-}
-
 var RootCmd = &cobra.Command{
-	Use:   "elasticsearch-operator",
-	Short: "An elasticsearch operator for Kubernetes",
-	Run: func(cmd *cobra.Command, args []string) {
+	Use:          "elasticsearch-operator",
+	Short:        "An elasticsearch operator for Kubernetes",
+	SilenceUsage: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return log.Configure(viper.GetString("log-level"), viper.GetString("log-format"))
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
 		logger := log.NewLogger()
 		defer logger.Infof("Elasticsearch Operator has stopped")
 
@@ -117,45 +47,136 @@ var RootCmd = &cobra.Command{
 
 		clientConfig, err := buildConfig(kubeconfig)
 		if err != nil {
-			logger.Panicf("%v", err)
+			return err
 		}
 
 		apiextensionsclientset, err := apiextensionsclient.NewForConfig(clientConfig)
 		if err != nil {
-			logger.Panicf("%v", err)
+			return err
 		}
 
-		crd, err := CreateCustomResourceDefinition(apiextensionsclientset)
-		if err != nil && !apierrors.IsAlreadyExists(err) {
-			logger.Panicf("%v", err)
+		// the definition is never deleted by the operator, as deleting it
+		// deletes every cluster
+		if viper.GetBool("manage-crd") {
+			if _, err := InstallCustomResourceDefinition(apiextensionsclientset); err != nil {
+				return err
+			}
+		} else if _, err := apiextensionsclientset.ApiextensionsV1beta1().CustomResourceDefinitions().Get(crdName, metav1.GetOptions{}); err != nil {
+			return fmt.Errorf("custom resource definition %s is not installed, run install-crds or pass --manage-crd: %v", crdName, err)
 		}
 
-		if crd != nil {
-			defer func() {
-				logger.Infof("Removing custom resource definition from cluster...")
-				deletePolicy := metav1.DeletePropagationForeground
-				err := apiextensionsclientset.ApiextensionsV1beta1().CustomResourceDefinitions().Delete(crd.Name, &metav1.DeleteOptions{
-					PropagationPolicy: &deletePolicy,
-				})
-				if err != nil {
-					logger.Errorf("Failed to remove custom resource definition")
-				} else {
-					logger.Infof("Custom resource definition removed.")
-				}
-			}()
+		options := Options{Namespaces: viper.GetStringSlice("namespace")}
+		if selector := viper.GetString("namespace-selector"); selector != "" {
+			if options.NamespaceSelector, err = labels.Parse(selector); err != nil {
+				return fmt.Errorf("invalid namespace selector: %v", err)
+			}
 		}
 
-		controller := NewController(clientConfig)
+		controller := NewController(clientConfig, options)
+
+		kubeclientset, err := kubernetes.NewForConfig(clientConfig)
+		if err != nil {
+			return err
+		}
 
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
-		go controller.Run(ctx)
+
+		runErr := make(chan error, 1)
+		stopped := make(chan struct{})
+		run := func() {
+			defer close(stopped)
+			runErr <- controller.Run(ctx)
+		}
+
+		var elector *leaderelection.LeaderElector
+		var lock *releasableLock
+		if viper.GetBool("leader-elect") {
+			identity, err := leaderElectionIdentity(viper.GetString("leader-elect-identity"))
+			if err != nil {
+				return err
+			}
+			electionConfig := leaderElectionConfig{
+				namespace:     operatorNamespace(viper.GetString("leader-elect-namespace")),
+				identity:      identity,
+				leaseDuration: viper.GetDuration("leader-elect-lease-duration"),
+				renewDeadline: viper.GetDuration("leader-elect-renew-deadline"),
+				retryPeriod:   viper.GetDuration("leader-elect-retry-period"),
+			}
+
+			elector, lock, err = newLeaderElector(kubeclientset, electionConfig, func(stop <-chan struct{}) {
+				go func() {
+					select {
+					case <-stop:
+						cancel()
+					case <-ctx.Done():
+					}
+				}()
+				run()
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if address := viper.GetString("http-address"); address != "" {
+			healthz := func() (string, error) {
+				return "ok", controller.Healthz()
+			}
+			readyz := func() (string, error) {
+				// replicas waiting for leadership are ready to take over
+				if elector != nil && !elector.IsLeader() {
+					return fmt.Sprintf("ok, standing by for leader %s", elector.GetLeader()), nil
+				}
+				return "ok", controller.Readyz()
+			}
+			server := serveHTTP(address, healthz, readyz)
+			defer server.Close()
+		}
+
+		// every replica serves the webhooks, they do not depend on the
+		// informers of the leader
+		if service := viper.GetString("webhook-service"); service != "" {
+			stopWebhooks := make(chan struct{})
+			defer close(stopWebhooks)
+			server, err := serveWebhooks(kubeclientset, apiextensionsclientset, webhookConfig{
+				address:   viper.GetString("webhook-address"),
+				namespace: operatorNamespace(viper.GetString("webhook-namespace")),
+				service:   service,
+			}, stopWebhooks)
+			if err != nil {
+				return err
+			}
+			defer server.Close()
+		}
+
+		if elector != nil {
+			go elector.Run()
+		} else {
+			go run()
+		}
 
 		select {
+		case err := <-runErr:
+			if err != nil {
+				return fmt.Errorf("controller stopped: %v", err)
+			}
+			return nil
 		case <-sigs:
-			logger.Infof("Elasticsearch Operator is stopping...")
-			return
 		}
+
+		logger.Infof("Elasticsearch Operator is stopping...")
+		if elector == nil || !elector.IsLeader() {
+			return nil
+		}
+
+		// finish the sync in progress before handing over to another replica
+		cancel()
+		<-stopped
+		if err := lock.release(); err != nil {
+			logger.Errorf("Failed to release leadership: %v", err)
+		}
+		return nil
 	},
 }
 
@@ -169,4 +190,26 @@ func Execute() {
 func init() {
 	RootCmd.PersistentFlags().StringP("kubeconfig", "f", "", "Path to kubeconfig")
 	viper.BindPFlag("kubeconfig", RootCmd.PersistentFlags().Lookup("kubeconfig"))
+	RootCmd.PersistentFlags().String("log-level", "info", "Log level, one of debug, info, warn or error")
+	viper.BindPFlag("log-level", RootCmd.PersistentFlags().Lookup("log-level"))
+	RootCmd.PersistentFlags().String("log-format", "console", "Log format, console or json")
+	viper.BindPFlag("log-format", RootCmd.PersistentFlags().Lookup("log-format"))
+
+	flags := RootCmd.Flags()
+	flags.String("http-address", ":8080", "Address to serve metrics, /healthz and /readyz on, empty to disable")
+	flags.StringSlice("namespace", nil, "Namespace to watch, may be repeated. Every namespace is watched unless a namespace or namespace selector is given")
+	flags.String("namespace-selector", "", "Label selector of additional namespaces to watch, such as elasticsearch=enabled")
+	flags.Bool("manage-crd", false, "Install the custom resource definition on startup, updating it in place")
+	flags.Bool("leader-elect", false, "Elect a leader among replicas of the operator before reconciling clusters")
+	flags.String("leader-elect-namespace", "", "Namespace of the leader election config map, defaults to the namespace of the operator")
+	flags.String("leader-elect-identity", "", "Identity of this replica in leader election, defaults to the hostname")
+	flags.Duration("leader-elect-lease-duration", 15*time.Second, "Time other replicas wait before taking over from a leader that stopped renewing")
+	flags.Duration("leader-elect-renew-deadline", 10*time.Second, "Time the leader retries renewing its lease before giving up leadership")
+	flags.Duration("leader-elect-retry-period", 2*time.Second, "Time between attempts to acquire or renew leadership")
+	flags.String("webhook-address", ":8443", "Address to serve the admission webhooks on")
+	flags.String("webhook-service", "", "Name of the service in front of the operator the api server sends admission and conversion requests to, empty to disable the webhooks")
+	flags.String("webhook-namespace", "", "Namespace of the webhook service, defaults to the namespace of the operator")
+	for _, name := range []string{"http-address", "webhook-address", "webhook-service", "webhook-namespace", "namespace", "namespace-selector", "manage-crd", "leader-elect", "leader-elect-namespace", "leader-elect-identity", "leader-elect-lease-duration", "leader-elect-renew-deadline", "leader-elect-retry-period"} {
+		viper.BindPFlag(name, flags.Lookup(name))
+	}
 }
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/webhook"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// webhookName names the webhook configurations of the operator
+	webhookName = "elasticsearch-operator"
+	// webhookSecretName holds the serving certificate shared by replicas
+	webhookSecretName = "elasticsearch-operator-webhook"
+)
+
+// webhookConfig holds the webhook flags
+type webhookConfig struct {
+	address   string
+	namespace string
+	service   string
+}
+
+// serveWebhooks serves the admission and conversion webhooks on address with
+// a certificate for the service in front of the operator, then registers
+// them with the api server. The certificate is rotated until stop is closed,
+// registering the webhooks again whenever its authority changes.
+func serveWebhooks(clientset kubernetes.Interface, apiextensionsclientset apiextensionsclient.Interface, config webhookConfig, stop <-chan struct{}) (*http.Server, error) {
+	logger := log.NewLogger()
+
+	rotator, err := webhook.NewRotator(clientset, config.namespace, webhookSecretName, config.service)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &http.Server{Addr: config.address, Handler: webhook.NewHandler(), TLSConfig: rotator.TLSConfig()}
+	go func() {
+		logger.Infof("Serving webhooks on %s", config.address)
+		if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("Failed to serve webhooks: %v", err)
+		}
+	}()
+
+	register := func(caBundle []byte) error {
+		registration := webhook.Config{
+			Name:      webhookName,
+			Namespace: config.namespace,
+			Service:   config.service,
+			CABundle:  caBundle,
+		}
+		if err := webhook.Register(clientset, registration); err != nil {
+			return fmt.Errorf("failed to register admission webhooks: %v", err)
+		}
+
+		served, err := webhook.RegisterConversion(apiextensionsclientset, registration)
+		if err != nil {
+			return fmt.Errorf("failed to register conversion webhook: %v", err)
+		}
+		if !served {
+			logger.Infof("Api server cannot call conversion webhooks, serving %s only", esV1.SchemeGroupVersion)
+		}
+		return nil
+	}
+	if err := register(rotator.CABundle()); err != nil {
+		server.Close()
+		return nil, err
+	}
+
+	go rotator.Run(stop, register)
+	return server, nil
+}
@@ -174,10 +174,8 @@ var _ = Describe("#Create: Creating a cluster", func() {
 
 				return false, err
 			}
-			// It can take awhile for the garbage collector
-			// to pick changes up = flaky test
-			// Requires doing some investigation into GC behaviour
-			// At worst, requires explicitly deleting some dependencies
+			// The operator deletes the dependencies of the cluster
+			// before removing its finalizer
 			something(check, events, time.Second*30)
 		})
 
@@ -50,10 +50,16 @@ rules:
   resources: ["customresourcedefinitions"]
   verbs: ["*"]
 - apiGroups: ["apps", "extensions"]
-  resources: ["deployments"]
+  resources: ["deployments", "statefulsets"]
   verbs: ["*"]
 - apiGroups: [""]
-  resources: ["services"]
+  resources: ["services", "configmaps", "pods", "persistentvolumeclaims"]
+  verbs: ["*"]
+- apiGroups: [""]
+  resources: ["secrets"]
+  verbs: ["get", "list", "watch"]
+- apiGroups: ["policy"]
+  resources: ["poddisruptionbudgets"]
   verbs: ["*"]
 - apiGroups: [""]
   resources: ["events"]
@@ -93,6 +99,18 @@ spec:
       containers:
       - name: elasticsearch-operator
         image: {{.Image}}
+        args: ["--manage-crd"]
+        ports:
+        - name: http
+          containerPort: 8080
+        livenessProbe:
+          httpGet:
+            path: /healthz
+            port: http
+        readinessProbe:
+          httpGet:
+            path: /readyz
+            port: http
 `
 
 func createClusterRoles(clientset kubernetes.Interface) ([]*rbacV1.ClusterRole, error) {
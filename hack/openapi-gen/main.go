@@ -0,0 +1,433 @@
+// openapi-gen writes the OpenAPI v3 validation schema of the Cluster custom
+// resource, derived from the Go types of an api package, to a Go constant
+// installed with the custom resource definition.
+//
+// Descriptions are taken from the doc comments of the fields and types.
+// Validation is added with markers in the doc comment of a field:
+//
+//	// +validation:Required
+//	// +validation:Minimum=0
+//	// +validation:Maximum=10
+//	// +validation:MinItems=1
+//	// +validation:Pattern=^[0-9]+$
+//	// +validation:Enum=http;https
+//
+// Named string types of the package are restricted to the values of their
+// constants.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const markerPrefix = "+validation:"
+
+// quantityPattern matches the serialized form of a resource.Quantity
+const quantityPattern = `^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`
+
+type schema struct {
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int64             `json:"minItems,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+func stringSchema() *schema {
+	return &schema{Type: "string"}
+}
+
+func stringMapSchema(values *schema) *schema {
+	return &schema{Type: "object", AdditionalProperties: values}
+}
+
+// external are the schemas of the types imported from other packages
+var external = map[string]func() *schema{
+	"corev1.LocalObjectReference": func() *schema {
+		return &schema{Type: "object", Properties: map[string]*schema{"name": stringSchema()}}
+	},
+	"corev1.ResourceRequirements": func() *schema {
+		return &schema{Type: "object", Properties: map[string]*schema{
+			"limits":   stringMapSchema(&schema{Type: "string", Pattern: quantityPattern}),
+			"requests": stringMapSchema(&schema{Type: "string", Pattern: quantityPattern}),
+		}}
+	},
+	// pod templates are validated when the statefulset is written
+	"corev1.PodTemplateSpec": func() *schema {
+		return &schema{Type: "object", Properties: map[string]*schema{
+			"metadata": {Type: "object"},
+			"spec":     {Type: "object"},
+		}}
+	},
+	"corev1.PersistentVolumeAccessMode": func() *schema {
+		return &schema{Type: "string", Enum: []string{"ReadWriteOnce", "ReadOnlyMany", "ReadWriteMany"}}
+	},
+	"corev1.ServiceType": func() *schema {
+		return &schema{Type: "string", Enum: []string{"ClusterIP", "NodePort", "LoadBalancer"}}
+	},
+	"corev1.ConditionStatus": func() *schema {
+		return &schema{Type: "string", Enum: []string{"True", "False", "Unknown"}}
+	},
+	"resource.Quantity": func() *schema {
+		return &schema{Type: "string", Pattern: quantityPattern}
+	},
+	"metav1.Time": func() *schema {
+		return &schema{Type: "string", Format: "date-time"}
+	},
+}
+
+type generator struct {
+	types map[string]*ast.TypeSpec
+	docs  map[string]*ast.CommentGroup
+	enums map[string][]string
+}
+
+func newGenerator(dir string) (*generator, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		name := info.Name()
+		return !strings.HasPrefix(name, "zz_generated") && !strings.HasSuffix(name, "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected a single package in %v, found %v", dir, len(pkgs))
+	}
+
+	g := &generator{
+		types: map[string]*ast.TypeSpec{},
+		docs:  map[string]*ast.CommentGroup{},
+		enums: map[string][]string{},
+	}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				decl, ok := decl.(*ast.GenDecl)
+				if !ok {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						g.types[spec.Name.Name] = spec
+						g.docs[spec.Name.Name] = decl.Doc
+						if spec.Doc != nil {
+							g.docs[spec.Name.Name] = spec.Doc
+						}
+					case *ast.ValueSpec:
+						g.addEnumValues(decl.Tok, spec)
+					}
+				}
+			}
+		}
+	}
+	return g, nil
+}
+
+func (g *generator) addEnumValues(tok token.Token, spec *ast.ValueSpec) {
+	typ, ok := spec.Type.(*ast.Ident)
+	if tok != token.CONST || !ok {
+		return
+	}
+	for _, value := range spec.Values {
+		lit, ok := value.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			continue
+		}
+		if s, err := strconv.Unquote(lit.Value); err == nil {
+			g.enums[typ.Name] = append(g.enums[typ.Name], s)
+		}
+	}
+}
+
+// resource returns the schema of the named custom resource. The api server
+// only allows properties at the root of the schema of a resource with a
+// status subresource, so the root is left untyped.
+func (g *generator) resource(name string) (*schema, error) {
+	s, err := g.named(name)
+	if err != nil {
+		return nil, err
+	}
+	s.Type = ""
+	s.Required = []string{"spec"}
+	return s, nil
+}
+
+func (g *generator) named(name string) (*schema, error) {
+	spec, ok := g.types[name]
+	if !ok {
+		return nil, fmt.Errorf("type %v not found", name)
+	}
+	if st, ok := spec.Type.(*ast.StructType); ok {
+		s, err := g.object(st)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", name, err)
+		}
+		s.Description = description(g.docs[name])
+		return s, nil
+	}
+	s, err := g.schemaFor(spec.Type)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", name, err)
+	}
+	if values, ok := g.enums[name]; ok && s.Type == "string" {
+		s.Enum = values
+	}
+	return s, nil
+}
+
+func (g *generator) object(st *ast.StructType) (*schema, error) {
+	s := &schema{Type: "object", Properties: map[string]*schema{}}
+	for _, field := range st.Fields.List {
+		name, inline := jsonName(field)
+		if name == "-" || isObjectMeta(field.Type) {
+			continue
+		}
+		if inline {
+			embedded, err := g.schemaFor(field.Type)
+			if err != nil {
+				return nil, err
+			}
+			for property, value := range embedded.Properties {
+				s.Properties[property] = value
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			if len(field.Names) == 0 {
+				continue
+			}
+			name = field.Names[0].Name
+		}
+
+		property, err := g.schemaFor(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %v", name, err)
+		}
+		if doc := description(field.Doc); doc != "" {
+			property.Description = doc
+		}
+		required, err := applyMarkers(property, field.Doc)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %v", name, err)
+		}
+		if required {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = property
+	}
+	sort.Strings(s.Required)
+	return s, nil
+}
+
+func (g *generator) schemaFor(expr ast.Expr) (*schema, error) {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return g.schemaFor(expr.X)
+	case *ast.ArrayType:
+		items, err := g.schemaFor(expr.Elt)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: "array", Items: items}, nil
+	case *ast.MapType:
+		if key, ok := expr.Key.(*ast.Ident); !ok || key.Name != "string" {
+			return nil, fmt.Errorf("unsupported map key %v", expr.Key)
+		}
+		values, err := g.schemaFor(expr.Value)
+		if err != nil {
+			return nil, err
+		}
+		return stringMapSchema(values), nil
+	case *ast.SelectorExpr:
+		name := fmt.Sprintf("%v.%v", expr.X, expr.Sel.Name)
+		s, ok := external[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %v", name)
+		}
+		return s(), nil
+	case *ast.Ident:
+		switch expr.Name {
+		case "string":
+			return &schema{Type: "string"}, nil
+		case "bool":
+			return &schema{Type: "boolean"}, nil
+		case "int", "int64":
+			return &schema{Type: "integer", Format: "int64"}, nil
+		case "int32":
+			return &schema{Type: "integer", Format: "int32"}, nil
+		case "float32", "float64":
+			return &schema{Type: "number"}, nil
+		}
+		return g.named(expr.Name)
+	}
+	return nil, fmt.Errorf("unsupported type %T", expr)
+}
+
+// isObjectMeta returns whether typ is the type or object metadata of a
+// resource, which is validated by the api server
+func isObjectMeta(typ ast.Expr) bool {
+	selector, ok := typ.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := selector.X.(*ast.Ident)
+	return ok && pkg.Name == "metav1" && strings.HasSuffix(selector.Sel.Name, "Meta")
+}
+
+// jsonName returns the name of a field in its json tag and whether the
+// field is inlined
+func jsonName(field *ast.Field) (string, bool) {
+	if field.Tag == nil {
+		return "", len(field.Names) == 0
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return "", false
+	}
+	parts := strings.Split(reflect.StructTag(tag).Get("json"), ",")
+	for _, option := range parts[1:] {
+		if option == "inline" {
+			return parts[0], true
+		}
+	}
+	return parts[0], len(field.Names) == 0 && parts[0] == ""
+}
+
+// description joins the lines of a doc comment, leaving out markers
+func description(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	var lines []string
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "+") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, " ")
+}
+
+// applyMarkers adds the validation markers of a doc comment to s and returns
+// whether the field is required
+func applyMarkers(s *schema, doc *ast.CommentGroup) (bool, error) {
+	if doc == nil {
+		return false, nil
+	}
+	required := false
+	for _, comment := range doc.List {
+		line := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		if !strings.HasPrefix(line, markerPrefix) {
+			continue
+		}
+		marker := strings.SplitN(strings.TrimPrefix(line, markerPrefix), "=", 2)
+		value := ""
+		if len(marker) == 2 {
+			value = marker[1]
+		}
+
+		switch marker[0] {
+		case "Required":
+			required = true
+		case "Minimum", "Maximum":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return false, fmt.Errorf("invalid marker %q: %v", line, err)
+			}
+			if marker[0] == "Minimum" {
+				s.Minimum = &n
+			} else {
+				s.Maximum = &n
+			}
+		case "MinItems":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return false, fmt.Errorf("invalid marker %q: %v", line, err)
+			}
+			s.MinItems = &n
+		case "Pattern":
+			s.Pattern = value
+		case "Enum":
+			s.Enum = strings.Split(value, ";")
+		default:
+			return false, fmt.Errorf("unknown marker %q", line)
+		}
+	}
+	return required, nil
+}
+
+func main() {
+	dir := flag.String("input-dir", "pkg/apis/es/v1", "directory of the api package")
+	kind := flag.String("kind", "Cluster", "type of the custom resource")
+	header := flag.String("go-header-file", "", "file holding the license header of the output")
+	output := flag.String("output-file", "zz_generated.openapi.go", "name of the output file in the input directory")
+	flag.Parse()
+
+	if err := generate(*dir, *kind, *header, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "openapi-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func generate(dir, kind, header, output string) error {
+	g, err := newGenerator(dir)
+	if err != nil {
+		return err
+	}
+	s, err := g.resource(kind)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if bytes.ContainsRune(data, '`') {
+		return fmt.Errorf("schema of %v contains a backtick", kind)
+	}
+
+	var buf bytes.Buffer
+	if header != "" {
+		license, err := ioutil.ReadFile(header)
+		if err != nil {
+			return err
+		}
+		buf.Write(license)
+		buf.WriteString("\n")
+	}
+	fmt.Fprintf(&buf, "// Code generated by openapi-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %v\n\n", filepath.Base(dir))
+	fmt.Fprintf(&buf, "// %vOpenAPISchema is the OpenAPI v3 validation schema of the %v resource\n", kind, kind)
+	fmt.Fprintf(&buf, "const %vOpenAPISchema = `%s`\n", kind, data)
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, output), src, 0644)
+}
@@ -35,6 +35,18 @@ CODEGEN_PKG=$GOPATH/src/k8s.io/code-generator
 
 $CODEGEN_PKG/generate-groups.sh all \
   github.com/matt-tyler/elasticsearch-operator/pkg/client github.com/matt-tyler/elasticsearch-operator/pkg/apis \
-  es:v1
+  es:v1,v2
+
+# generate the conversions of v1 to and from the v2 hub
+go run $CODEGEN_PKG/cmd/conversion-gen \
+  --input-dirs github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1 \
+  -O zz_generated.conversion \
+  --go-header-file $CODEGEN_PKG/hack/boilerplate.go.txt
+
+# regenerate the validation schemas of the custom resource definition
+for version in v1 v2; do
+  go run ./hack/openapi-gen --input-dir pkg/apis/es/$version --go-header-file $CODEGEN_PKG/hack/boilerplate.go.txt
+done
+
 # To use your own boilerplate text append:
 #   --go-header-file ${SCRIPT_ROOT}/hack/custom-boilerplate.go.txt
\ No newline at end of file
@@ -0,0 +1,98 @@
+package v1
+
+import (
+	"github.com/matt-tyler/elasticsearch-operator/pkg/apis/es"
+	v2 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v2"
+	"k8s.io/apimachinery/pkg/conversion"
+)
+
+// NameAnnotation keeps the spec.name of a v1 cluster once converted to a
+// version without it, so converting back restores it
+const NameAnnotation = es.GroupName + "/v1-spec-name"
+
+// ConvertTo converts the cluster to the hub version
+func (c *Cluster) ConvertTo(hub *v2.Cluster) error {
+	if err := Convert_v1_Cluster_To_v2_Cluster(c.DeepCopy(), hub, nil); err != nil {
+		return err
+	}
+	hub.APIVersion = v2.SchemeGroupVersion.String()
+	hub.Kind = "Cluster"
+	return nil
+}
+
+// ConvertFrom converts the hub version to the cluster
+func (c *Cluster) ConvertFrom(hub *v2.Cluster) error {
+	if err := Convert_v2_Cluster_To_v1_Cluster(hub.DeepCopy(), c, nil); err != nil {
+		return err
+	}
+	c.APIVersion = SchemeGroupVersion.String()
+	c.Kind = "Cluster"
+	return nil
+}
+
+func Convert_v1_Cluster_To_v2_Cluster(in *Cluster, out *v2.Cluster, s conversion.Scope) error {
+	if err := autoConvert_v1_Cluster_To_v2_Cluster(in, out, s); err != nil {
+		return err
+	}
+	if in.Spec.Name != "" {
+		annotations := make(map[string]string, len(in.Annotations)+1)
+		for k, v := range in.Annotations {
+			annotations[k] = v
+		}
+		annotations[NameAnnotation] = in.Spec.Name
+		out.Annotations = annotations
+	}
+	return nil
+}
+
+func Convert_v2_Cluster_To_v1_Cluster(in *v2.Cluster, out *Cluster, s conversion.Scope) error {
+	if err := autoConvert_v2_Cluster_To_v1_Cluster(in, out, s); err != nil {
+		return err
+	}
+	name, ok := in.Annotations[NameAnnotation]
+	if !ok {
+		return nil
+	}
+	out.Spec.Name = name
+	annotations := map[string]string{}
+	for k, v := range in.Annotations {
+		if k != NameAnnotation {
+			annotations[k] = v
+		}
+	}
+	out.Annotations = nil
+	if len(annotations) > 0 {
+		out.Annotations = annotations
+	}
+	return nil
+}
+
+// Convert_v1_ClusterSpec_To_v2_ClusterSpec groups the deletion settings.
+// spec.name is kept by Convert_v1_Cluster_To_v2_Cluster.
+func Convert_v1_ClusterSpec_To_v2_ClusterSpec(in *ClusterSpec, out *v2.ClusterSpec, s conversion.Scope) error {
+	if err := autoConvert_v1_ClusterSpec_To_v2_ClusterSpec(in, out, s); err != nil {
+		return err
+	}
+	out.Deletion.Policy = v2.DeletionPolicy(in.DeletionPolicy)
+	if in.Snapshot != nil {
+		out.Deletion.Snapshot = &v2.SnapshotSpec{}
+		if err := Convert_v1_SnapshotSpec_To_v2_SnapshotSpec(in.Snapshot, out.Deletion.Snapshot, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Convert_v2_ClusterSpec_To_v1_ClusterSpec(in *v2.ClusterSpec, out *ClusterSpec, s conversion.Scope) error {
+	if err := autoConvert_v2_ClusterSpec_To_v1_ClusterSpec(in, out, s); err != nil {
+		return err
+	}
+	out.DeletionPolicy = DeletionPolicy(in.Deletion.Policy)
+	if in.Deletion.Snapshot != nil {
+		out.Snapshot = &SnapshotSpec{}
+		if err := Convert_v2_SnapshotSpec_To_v1_SnapshotSpec(in.Deletion.Snapshot, out.Snapshot, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package v1
+
+import (
+	"testing"
+
+	fuzz "github.com/google/gofuzz"
+	v2 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v2"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/diff"
+)
+
+const fuzzIterations = 1000
+
+func newFuzzer() *fuzz.Fuzzer {
+	return fuzz.New().NilChance(.2).Funcs(
+		func(q *resource.Quantity, c fuzz.Continue) {
+			*q = *resource.NewQuantity(c.Int63n(1<<40), resource.BinarySI)
+		},
+		// a fully fuzzed pod template makes each iteration slow
+		func(t *corev1.PodTemplateSpec, c fuzz.Continue) {
+			c.Fuzz(&t.Labels)
+			c.Fuzz(&t.Spec.NodeSelector)
+			c.Fuzz(&t.Spec.Tolerations)
+		},
+	)
+}
+
+func TestRoundTripToHub(t *testing.T) {
+	f := newFuzzer()
+	for i := 0; i < fuzzIterations; i++ {
+		cluster := &Cluster{}
+		f.Fuzz(cluster)
+		cluster.APIVersion = SchemeGroupVersion.String()
+		cluster.Kind = "Cluster"
+
+		hub := &v2.Cluster{}
+		if err := cluster.ConvertTo(hub); err != nil {
+			t.Fatalf("failed to convert to hub: %v", err)
+		}
+		roundTripped := &Cluster{}
+		if err := roundTripped.ConvertFrom(hub); err != nil {
+			t.Fatalf("failed to convert from hub: %v", err)
+		}
+
+		if !apiequality.Semantic.DeepEqual(cluster, roundTripped) {
+			t.Fatalf("cluster changed by round trip through the hub:\n%s", diff.ObjectReflectDiff(cluster, roundTripped))
+		}
+	}
+}
+
+func TestRoundTripFromHub(t *testing.T) {
+	f := newFuzzer()
+	for i := 0; i < fuzzIterations; i++ {
+		hub := &v2.Cluster{}
+		f.Fuzz(hub)
+		hub.APIVersion = v2.SchemeGroupVersion.String()
+		hub.Kind = "Cluster"
+
+		cluster := &Cluster{}
+		if err := cluster.ConvertFrom(hub); err != nil {
+			t.Fatalf("failed to convert from hub: %v", err)
+		}
+		roundTripped := &v2.Cluster{}
+		if err := cluster.ConvertTo(roundTripped); err != nil {
+			t.Fatalf("failed to convert to hub: %v", err)
+		}
+
+		if !apiequality.Semantic.DeepEqual(hub, roundTripped) {
+			t.Fatalf("hub changed by round trip through v1:\n%s", diff.ObjectReflectDiff(hub, roundTripped))
+		}
+	}
+}
+
+func TestSpecNameRoundTrip(t *testing.T) {
+	cluster := &Cluster{}
+	cluster.Spec.Name = "logs"
+
+	hub := &v2.Cluster{}
+	if err := cluster.ConvertTo(hub); err != nil {
+		t.Fatal(err)
+	}
+	if name := hub.Annotations[NameAnnotation]; name != "logs" {
+		t.Errorf("expected spec.name kept in annotation %s, got %q", NameAnnotation, name)
+	}
+
+	roundTripped := &Cluster{}
+	if err := roundTripped.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if roundTripped.Spec.Name != "logs" || roundTripped.Annotations != nil {
+		t.Errorf("expected spec.name restored without annotations, got %q and %v", roundTripped.Spec.Name, roundTripped.Annotations)
+	}
+}
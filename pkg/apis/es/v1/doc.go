@@ -1,4 +1,5 @@
 // +k8s:deepcopy-gen=package
+// +k8s:conversion-gen=github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v2
 
 // Package v1 is the v1 version of the API.
 // +groupName=es.matt-tyler.github.com
@@ -1,23 +1,354 @@
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const ResourcePlural = "clusters"
 
+const (
+	// DefaultVersion is the elasticsearch version of a cluster without
+	// spec.version
+	DefaultVersion = "6.1.1"
+	// DefaultStorageSize is the size of a storage spec without one
+	DefaultStorageSize = "10Gi"
+)
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 type Cluster struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
-	Spec              ClusterSpec `json:"spec"`
+	Spec              ClusterSpec   `json:"spec"`
+	Status            ClusterStatus `json:"status,omitempty"`
 }
 
 type ClusterSpec struct {
 	Name string `json:"name"`
-	Size int    `json:"size"`
+	// Version of elasticsearch run by the cluster, defaults to 6.1.1
+	// +validation:Pattern=^[0-9]+\.[0-9]+\.[0-9]+$
+	Version string `json:"version,omitempty"`
+	// Image overrides the elasticsearch image, for example to pull it from a
+	// private registry. It must run the elasticsearch version of the cluster.
+	Image string `json:"image,omitempty"`
+	// ImagePullSecrets are used to pull the elasticsearch image
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// Size is the number of data nodes in the cluster. It is the replica
+	// count of any node pool with the data role that does not set its own,
+	// and is what the scale subresource scales.
+	// +validation:Minimum=0
+	Size int `json:"size"`
+	// Storage is the default storage of every node pool
+	Storage StorageSpec `json:"storage,omitempty"`
+	// NodePools are the groups of nodes making up the cluster. When empty the
+	// cluster has a single master node and Size data nodes.
+	NodePools []NodePool `json:"nodePools,omitempty"`
+	// Connection configures how the operator reaches the REST api of the
+	// cluster, by default over plain http without credentials
+	Connection *ConnectionSpec `json:"connection,omitempty"`
+	// HTTP configures the service clients use to reach the cluster
+	HTTP *HTTPSpec `json:"http,omitempty"`
+	// Config holds elasticsearch.yml settings of every node, such as
+	// indices.memory.index_buffer_size. Settings managed by the operator,
+	// such as cluster.name and the discovery settings, cannot be set.
+	Config map[string]string `json:"config,omitempty"`
+	// DeletionPolicy is the cleanup performed when the cluster is deleted,
+	// defaults to Delete
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// Snapshot configures the repository the final snapshot of the Snapshot
+	// deletion policy is written to
+	Snapshot *SnapshotSpec `json:"snapshot,omitempty"`
+}
+
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete removes every resource of the cluster including
+	// the persistent volume claims holding its data
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain removes the cluster but keeps the persistent
+	// volume claims, so a cluster of the same name picks its data up again
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicySnapshot snapshots every index before behaving as Delete.
+	// Deletion waits until the snapshot succeeds, changing the policy to
+	// Delete abandons it.
+	DeletionPolicySnapshot DeletionPolicy = "Snapshot"
+)
+
+// SnapshotSpec describes an elasticsearch snapshot repository
+type SnapshotSpec struct {
+	// Repository is the name of the snapshot repository
+	// +validation:Required
+	Repository string `json:"repository"`
+	// Type of the repository, such as fs or s3. When set the repository is
+	// registered with Settings before snapshotting, otherwise it must
+	// already be registered.
+	Type     string            `json:"type,omitempty"`
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+// HTTPSpec configures the client service load balancing requests across the
+// data and coordinating nodes of the cluster
+type HTTPSpec struct {
+	// ServiceType is ClusterIP, NodePort or LoadBalancer. Defaults to ClusterIP.
+	ServiceType corev1.ServiceType `json:"serviceType,omitempty"`
+	// Annotations are added to the service, for example to configure a
+	// cloud load balancer
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// LoadBalancerSourceRanges restricts the clients of a LoadBalancer service
+	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
+}
+
+// ConnectionSpec configures the authentication and TLS used by the operator
+// when talking to the cluster
+type ConnectionSpec struct {
+	// Scheme of the REST api, http or https. Defaults to http.
+	// +validation:Enum=http;https
+	Scheme string `json:"scheme,omitempty"`
+	// CredentialsSecret names a secret holding the username and password
+	// keys used for basic auth
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+	// CASecret names a secret holding the ca.crt key used to verify the
+	// certificate of the cluster
+	CASecret string `json:"caSecret,omitempty"`
+	// InsecureSkipVerify disables verification of the cluster certificate
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+type NodeRole string
+
+const (
+	NodeRoleMaster NodeRole = "master"
+	NodeRoleData   NodeRole = "data"
+	NodeRoleIngest NodeRole = "ingest"
+	// NodeRoleCoordinating marks a coordinating-only node and may not be
+	// combined with other roles
+	NodeRoleCoordinating NodeRole = "coordinating"
+	NodeRoleML           NodeRole = "ml"
+)
+
+// NodePool is a group of identically configured elasticsearch nodes
+type NodePool struct {
+	// Name of the pool, used in the names of its statefulset and services
+	// +validation:Required
+	// +validation:Pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?$
+	Name string `json:"name"`
+	// Roles of the nodes in the pool
+	// +validation:Required
+	// +validation:MinItems=1
+	Roles []NodeRole `json:"roles"`
+	// Replicas defaults to spec.size for pools with the data role and to 1
+	// otherwise
+	// +validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Resources of the elasticsearch container of each node. Memory
+	// requests default to the memory limit.
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Heap overrides the JVM heap size of the nodes, which defaults to half
+	// the memory limit capped at 31Gi so the JVM keeps using compressed
+	// object pointers. It may not exceed the memory limit.
+	Heap *resource.Quantity `json:"heap,omitempty"`
+	// Storage overrides spec.storage for the nodes of this pool.
+	// Coordinating-only pools do not claim storage.
+	Storage *StorageSpec `json:"storage,omitempty"`
+	// Config holds elasticsearch.yml settings of the nodes of this pool,
+	// taking precedence over spec.config
+	Config map[string]string `json:"config,omitempty"`
+	// PodTemplate is strategically merged over the pod template built by the
+	// operator, to schedule the nodes or add volumes and sidecars. Containers
+	// and volumes are merged by name, the operator's container being named
+	// elasticsearch. Its ports and discovery env cannot be overridden.
+	PodTemplate *corev1.PodTemplateSpec `json:"podTemplate,omitempty"`
+}
+
+// HasRole returns whether role is one of the roles of the pool
+func (p *NodePool) HasRole(role NodeRole) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCoordinatingOnly returns whether the nodes of the pool only route requests
+func (p *NodePool) IsCoordinatingOnly() bool {
+	return p.HasRole(NodeRoleCoordinating)
+}
+
+// DefaultedNodePools returns the node pools of a cluster with defaults
+// applied. A cluster without node pools gets a single master node and Size
+// data nodes.
+func (s *ClusterSpec) DefaultedNodePools() []NodePool {
+	pools := s.NodePools
+	if len(pools) == 0 {
+		pools = []NodePool{{
+			Name:  "master",
+			Roles: []NodeRole{NodeRoleMaster},
+		}, {
+			Name:  "data",
+			Roles: []NodeRole{NodeRoleData, NodeRoleIngest},
+		}}
+	}
+
+	defaulted := make([]NodePool, len(pools))
+	for i := range pools {
+		pool := pools[i].DeepCopy()
+		if pool.Replicas == nil {
+			replicas := int32(1)
+			if pool.HasRole(NodeRoleData) {
+				replicas = int32(s.Size)
+			}
+			pool.Replicas = &replicas
+		}
+		if pool.Storage == nil && !pool.IsCoordinatingOnly() {
+			pool.Storage = s.Storage.DeepCopy()
+		}
+		defaulted[i] = *pool
+	}
+	return defaulted
+}
+
+// StorageSpec describes the persistent volume claimed by each data node
+type StorageSpec struct {
+	// StorageClassName of the claim, the cluster default is used when unset
+	StorageClassName *string `json:"storageClassName,omitempty"`
+	// Size of the claim, defaults to 10Gi
+	Size resource.Quantity `json:"size,omitempty"`
+	// AccessModes of the claim, defaults to ReadWriteOnce
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+}
+
+type ClusterPhase string
+
+const (
+	// ClusterPending means no elasticsearch nodes are ready yet
+	ClusterPending ClusterPhase = "Pending"
+	// ClusterRunning means every node of the cluster is ready
+	ClusterRunning ClusterPhase = "Running"
+	// ClusterDegraded means the cluster has fewer ready nodes than desired
+	ClusterDegraded ClusterPhase = "Degraded"
+	// ClusterTerminating means the cluster has been deleted and the operator
+	// is carrying out its deletion policy
+	ClusterTerminating ClusterPhase = "Terminating"
+)
+
+type ClusterHealth string
+
+const (
+	ClusterHealthGreen   ClusterHealth = "green"
+	ClusterHealthYellow  ClusterHealth = "yellow"
+	ClusterHealthRed     ClusterHealth = "red"
+	ClusterHealthUnknown ClusterHealth = "unknown"
+)
+
+type ClusterConditionType string
+
+const (
+	// ClusterReady is true when all desired nodes are ready
+	ClusterReady ClusterConditionType = "Ready"
+	// ClusterProgressing is true while child resources are being rolled out
+	ClusterProgressing ClusterConditionType = "Progressing"
+	// ClusterDegradedCondition is true when the cluster is not progressing
+	// but has fewer ready nodes than desired, or failed to reconcile
+	ClusterDegradedCondition ClusterConditionType = "Degraded"
+	// ClusterVersionSupported is false when the operator cannot run the
+	// elasticsearch version requested by the spec
+	ClusterVersionSupported ClusterConditionType = "VersionSupported"
+	// ClusterScaleDownBlocked is true when data nodes cannot be removed as
+	// the remaining nodes could not hold every replica
+	ClusterScaleDownBlocked ClusterConditionType = "ScaleDownBlocked"
+)
+
+type ClusterCondition struct {
+	Type               ClusterConditionType   `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	ObservedGeneration int64                  `json:"observedGeneration,omitempty"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
+}
+
+// RoleStatus reports the desired and ready node counts of a single node role
+type RoleStatus struct {
+	Role    string `json:"role"`
+	Desired int32  `json:"desired"`
+	Ready   int32  `json:"ready"`
+}
+
+type ClusterStatus struct {
+	// ObservedGeneration is the most recent generation of the Cluster spec
+	// acted on by the controller
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+	Phase              ClusterPhase       `json:"phase,omitempty"`
+	Health             ClusterHealth      `json:"health,omitempty"`
+	Roles              []RoleStatus       `json:"roles,omitempty"`
+	Conditions         []ClusterCondition `json:"conditions,omitempty"`
+	// Bootstrapped is set once a cluster of version 7 or later has elected
+	// its first master, after which cluster.initial_master_nodes is removed
+	Bootstrapped bool `json:"bootstrapped,omitempty"`
+	// VotingConfigExclusions are the master nodes excluded from voting while
+	// they are removed from the cluster
+	VotingConfigExclusions []string `json:"votingConfigExclusions,omitempty"`
+	// ExcludedNodes are the data nodes shards are being moved off before they
+	// are removed by scaling down
+	ExcludedNodes []string `json:"excludedNodes,omitempty"`
+	// Upgrade records the progress of restarting a node onto the latest
+	// pod template, so an interrupted upgrade can be resumed
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
+	// Endpoint is the url clients use to reach the REST api of the cluster
+	Endpoint string `json:"endpoint,omitempty"`
+	// Deletion reports the progress of the deletion policy once the cluster
+	// has been deleted
+	Deletion *DeletionStatus `json:"deletion,omitempty"`
+	// Replicas is the number of data nodes currently running, reported by
+	// the scale subresource
+	Replicas int32 `json:"replicas,omitempty"`
+	// Selector is the label selector of the data nodes, used by horizontal
+	// pod autoscalers to find the pods they scale
+	Selector string `json:"selector,omitempty"`
+}
+
+type DeletionPhase string
+
+const (
+	// DeletionSnapshotting means the final snapshot is being taken
+	DeletionSnapshotting DeletionPhase = "Snapshotting"
+	// DeletionDeletingResources means the resources of the cluster are being
+	// removed, after which the finalizer is removed
+	DeletionDeletingResources DeletionPhase = "DeletingResources"
+)
+
+// DeletionStatus describes the cleanup of a deleted cluster
+type DeletionStatus struct {
+	Phase DeletionPhase `json:"phase"`
+	// Snapshot is the name of the final snapshot
+	Snapshot string `json:"snapshot,omitempty"`
+	// Message explains why deletion is not progressing
+	Message string `json:"message,omitempty"`
+}
+
+type UpgradePhase string
+
+const (
+	// UpgradeRestarting means the pod has been chosen for restart, and
+	// shard allocation is being limited to primaries before it is deleted
+	UpgradeRestarting UpgradePhase = "Restarting"
+	// UpgradeWaitingForNode means the pod has been deleted and the controller
+	// is waiting for its replacement to rejoin the cluster
+	UpgradeWaitingForNode UpgradePhase = "WaitingForNode"
+	// UpgradeWaitingForHealth means allocation has been re-enabled and the
+	// controller is waiting for the cluster to return to yellow or green
+	UpgradeWaitingForHealth UpgradePhase = "WaitingForHealth"
+)
+
+// UpgradeStatus describes the node currently being restarted by a rolling upgrade
+type UpgradeStatus struct {
+	Pod   string       `json:"pod"`
+	Phase UpgradePhase `json:"phase"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
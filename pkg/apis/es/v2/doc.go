@@ -0,0 +1,6 @@
+// +k8s:deepcopy-gen=package
+
+// Package v2 is the v2 version of the API, the hub every other version
+// converts through.
+// +groupName=es.matt-tyler.github.com
+package v2
@@ -20,6 +20,7 @@ package versioned
 
 import (
 	esv1 "github.com/matt-tyler/elasticsearch-operator/pkg/client/clientset/versioned/typed/es/v1"
+	esv2 "github.com/matt-tyler/elasticsearch-operator/pkg/client/clientset/versioned/typed/es/v2"
 	discovery "k8s.io/client-go/discovery"
 	rest "k8s.io/client-go/rest"
 	flowcontrol "k8s.io/client-go/util/flowcontrol"
@@ -28,8 +29,9 @@ import (
 type Interface interface {
 	Discovery() discovery.DiscoveryInterface
 	EsV1() esv1.EsV1Interface
+	EsV2() esv2.EsV2Interface
 	// Deprecated: please explicitly pick a version if possible.
-	Es() esv1.EsV1Interface
+	Es() esv2.EsV2Interface
 }
 
 // Clientset contains the clients for groups. Each group has exactly one
@@ -37,6 +39,7 @@ type Interface interface {
 type Clientset struct {
 	*discovery.DiscoveryClient
 	esV1 *esv1.EsV1Client
+	esV2 *esv2.EsV2Client
 }
 
 // EsV1 retrieves the EsV1Client
@@ -44,10 +47,15 @@ func (c *Clientset) EsV1() esv1.EsV1Interface {
 	return c.esV1
 }
 
+// EsV2 retrieves the EsV2Client
+func (c *Clientset) EsV2() esv2.EsV2Interface {
+	return c.esV2
+}
+
 // Deprecated: Es retrieves the default version of EsClient.
 // Please explicitly pick a version.
-func (c *Clientset) Es() esv1.EsV1Interface {
-	return c.esV1
+func (c *Clientset) Es() esv2.EsV2Interface {
+	return c.esV2
 }
 
 // Discovery retrieves the DiscoveryClient
@@ -70,6 +78,10 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 	if err != nil {
 		return nil, err
 	}
+	cs.esV2, err = esv2.NewForConfig(&configShallowCopy)
+	if err != nil {
+		return nil, err
+	}
 
 	cs.DiscoveryClient, err = discovery.NewDiscoveryClientForConfig(&configShallowCopy)
 	if err != nil {
@@ -83,6 +95,7 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 func NewForConfigOrDie(c *rest.Config) *Clientset {
 	var cs Clientset
 	cs.esV1 = esv1.NewForConfigOrDie(c)
+	cs.esV2 = esv2.NewForConfigOrDie(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClientForConfigOrDie(c)
 	return &cs
@@ -92,6 +105,7 @@ func NewForConfigOrDie(c *rest.Config) *Clientset {
 func New(c rest.Interface) *Clientset {
 	var cs Clientset
 	cs.esV1 = esv1.New(c)
+	cs.esV2 = esv2.New(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClient(c)
 	return &cs
@@ -22,6 +22,8 @@ import (
 	clientset "github.com/matt-tyler/elasticsearch-operator/pkg/client/clientset/versioned"
 	esv1 "github.com/matt-tyler/elasticsearch-operator/pkg/client/clientset/versioned/typed/es/v1"
 	fakeesv1 "github.com/matt-tyler/elasticsearch-operator/pkg/client/clientset/versioned/typed/es/v1/fake"
+	esv2 "github.com/matt-tyler/elasticsearch-operator/pkg/client/clientset/versioned/typed/es/v2"
+	fakeesv2 "github.com/matt-tyler/elasticsearch-operator/pkg/client/clientset/versioned/typed/es/v2/fake"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/discovery"
@@ -76,7 +78,12 @@ func (c *Clientset) EsV1() esv1.EsV1Interface {
 	return &fakeesv1.FakeEsV1{Fake: &c.Fake}
 }
 
-// Es retrieves the EsV1Client
-func (c *Clientset) Es() esv1.EsV1Interface {
-	return &fakeesv1.FakeEsV1{Fake: &c.Fake}
+// EsV2 retrieves the EsV2Client
+func (c *Clientset) EsV2() esv2.EsV2Interface {
+	return &fakeesv2.FakeEsV2{Fake: &c.Fake}
+}
+
+// Es retrieves the EsV2Client
+func (c *Clientset) Es() esv2.EsV2Interface {
+	return &fakeesv2.FakeEsV2{Fake: &c.Fake}
 }
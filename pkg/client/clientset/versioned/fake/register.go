@@ -20,6 +20,7 @@ package fake
 
 import (
 	esv1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	esv2 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v2"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
@@ -32,6 +33,7 @@ var codecs = serializer.NewCodecFactory(scheme)
 var parameterCodec = runtime.NewParameterCodec(scheme)
 var localSchemeBuilder = runtime.SchemeBuilder{
 	esv1.AddToScheme,
+	esv2.AddToScheme,
 }
 
 // AddToScheme adds all types of this clientset into the given scheme. This allows composition
@@ -100,6 +100,18 @@ func (c *FakeClusters) Update(cluster *esv1.Cluster) (result *esv1.Cluster, err
 	return obj.(*esv1.Cluster), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeClusters) UpdateStatus(cluster *esv1.Cluster) (*esv1.Cluster, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(clustersResource, "status", c.ns, cluster), &esv1.Cluster{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*esv1.Cluster), err
+}
+
 // Delete takes name of the cluster and deletes it. Returns an error if one occurs.
 func (c *FakeClusters) Delete(name string, options *v1.DeleteOptions) error {
 	_, err := c.Fake.
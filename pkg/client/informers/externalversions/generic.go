@@ -22,6 +22,7 @@ import (
 	"fmt"
 
 	v1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	v2 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v2"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	cache "k8s.io/client-go/tools/cache"
 )
@@ -56,6 +57,10 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	case v1.SchemeGroupVersion.WithResource("clusters"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Es().V1().Clusters().Informer()}, nil
 
+		// Group=es.matt-tyler.github.com, Version=v2
+	case v2.SchemeGroupVersion.WithResource("clusters"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Es().V2().Clusters().Informer()}, nil
+
 	}
 
 	return nil, fmt.Errorf("no informer found for %v", resource)
@@ -3,23 +3,28 @@ package controller
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
 	clientset "github.com/matt-tyler/elasticsearch-operator/pkg/client/clientset/versioned"
 	"github.com/matt-tyler/elasticsearch-operator/pkg/client/clientset/versioned/scheme"
-	informers "github.com/matt-tyler/elasticsearch-operator/pkg/client/informers/externalversions"
 	listers "github.com/matt-tyler/elasticsearch-operator/pkg/client/listers/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/esclient"
 	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/validation"
+	"github.com/prometheus/client_golang/prometheus"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/runtime"
 
 	clusterscheme "github.com/matt-tyler/elasticsearch-operator/pkg/client/clientset/versioned/scheme"
+	v1beta2 "k8s.io/api/apps/v1beta2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/apimachinery/pkg/util/wait"
-	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	appslisters "k8s.io/client-go/listers/apps/v1beta2"
@@ -31,6 +36,14 @@ import (
 )
 
 const maxRetries = 5
+
+// requeueInterval is how long to wait before checking on a cluster that is
+// waiting for elasticsearch, such as during a rolling upgrade
+const requeueInterval = 10 * time.Second
+
+// cacheSyncTimeout is how long Run waits for the informers to sync before
+// giving up
+const cacheSyncTimeout = 5 * time.Minute
 const controllerAgentName = "elasticsearch-cluster-controller"
 
 const (
@@ -41,6 +54,14 @@ const (
 	// to sync due to a resource already existing
 	ErrResourceExists = "ErrResourceExists"
 
+	// ErrUnsupportedVersion is used as part of the Event 'reason' when a cluster
+	// requests an elasticsearch version the controller cannot run
+	ErrUnsupportedVersion = "ErrUnsupportedVersion"
+
+	// ErrInvalidSpec is used as part of the Event 'reason' when a cluster fails
+	// to sync due to a spec that cannot be turned into resources
+	ErrInvalidSpec = "ErrInvalidSpec"
+
 	// MessageResourceExists is the message used for events when a resource
 	// fails to sync due to it already existing
 	MessageResourceExists = "Resource %q already exists and is not managed by controller"
@@ -48,6 +69,14 @@ const (
 	// MessageResourceSynced is the messaged used for an Event fire when a Cluster
 	// is successfully synced.
 	MessageResourceSynced = "Resource %q synced successfully"
+
+	// SuccessUpdated is used as part of the Event 'reason' when a child resource
+	// is updated to match the cluster spec
+	SuccessUpdated = "Updated"
+
+	// MessageResourceUpdated is the message used for events when a child
+	// resource has drifted from the cluster spec and is updated
+	MessageResourceUpdated = "Resource %q updated to match spec: %s"
 )
 
 type Controller struct {
@@ -56,42 +85,35 @@ type Controller struct {
 	kubeclientset kubernetes.Interface
 	esclientset   clientset.Interface
 
-	kubeInformerFactory kubeinformers.SharedInformerFactory
-	esInformerFactory   informers.SharedInformerFactory
+	options    Options
+	namespaces *namespaces
 
-	clustersSynced    cache.InformerSynced
-	servicesSynced    cache.InformerSynced
-	deploymentsSynced cache.InformerSynced
+	clusterLister     listers.ClusterLister
+	serviceLister     corelisters.ServiceLister
+	statefulSetLister appslisters.StatefulSetLister
+	configMapLister   corelisters.ConfigMapLister
+	podLister         corelisters.PodLister
 
-	clusterLister    listers.ClusterLister
-	serviceLister    corelisters.ServiceLister
-	deploymentLister appslisters.DeploymentLister
+	// newESClient builds the clients used to talk to elasticsearch
+	newESClient func(esclient.Config) (esclient.Interface, error)
 
 	queue workqueue.RateLimitingInterface
 
+	// running is set while Run is running, and syncStarted holds the time
+	// in nanoseconds the sync in progress started, see Healthz and Readyz
+	running     int32
+	syncStarted int64
+
 	recorder record.EventRecorder
 }
 
-func NewController(config *rest.Config) *Controller {
-	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+func NewController(config *rest.Config, options Options) *Controller {
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "clusters")
 
 	kubeclientset := kubernetes.NewForConfigOrDie(config)
 	esclientset := clientset.NewForConfigOrDie(config)
 
-	resyncPeriod := 0 * time.Second
-
-	listOptions := func(options *metav1.ListOptions) {
-		options.LabelSelector = labels.Set(map[string]string{
-			"operator": "elasticsearch-operator",
-		}).AsSelector().String()
-	}
-
-	kubeInformerFactory := kubeinformers.NewFilteredSharedInformerFactory(kubeclientset, resyncPeriod, "", listOptions)
-	esInformerFactory := informers.NewSharedInformerFactory(esclientset, resyncPeriod)
-
-	clusterInformer := esInformerFactory.Es().V1().Clusters()
-	serviceInformer := kubeInformerFactory.Core().V1().Services()
-	deploymentInformer := kubeInformerFactory.Apps().V1beta2().Deployments()
+	watched := &namespaces{informers: map[string]*namespaceInformers{}}
 
 	logger := log.NewLogger()
 
@@ -102,60 +124,31 @@ func NewController(config *rest.Config) *Controller {
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName})
 
 	controller := &Controller{
-		logger,
-		kubeclientset,
-		esclientset,
-		kubeInformerFactory,
-		esInformerFactory,
-		clusterInformer.Informer().HasSynced,
-		serviceInformer.Informer().HasSynced,
-		deploymentInformer.Informer().HasSynced,
-		clusterInformer.Lister(),
-		serviceInformer.Lister(),
-		deploymentInformer.Lister(),
-		queue,
-		recorder,
-	}
-
-	clusterInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			if key, err := cache.MetaNamespaceKeyFunc(obj); err == nil {
-				queue.Add(key)
-			}
-		},
-		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
-			if key, err := cache.MetaNamespaceKeyFunc(newObj); err == nil {
-				queue.Add(key)
-			}
-		},
-		DeleteFunc: func(obj interface{}) {
-			if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
-				queue.Add(key)
-			}
-		},
-	})
-
-	serviceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: controller.handleObject,
-		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
-			newSvc := newObj.(*corev1.Service)
-			oldSvc := oldObj.(*corev1.Service)
-			if newSvc.ResourceVersion == oldSvc.ResourceVersion {
-				return
-			}
-			controller.handleObject(newObj)
-		},
-		DeleteFunc: controller.handleObject,
-	})
-
-	ctx := context.Background()
+		Logger:            logger,
+		kubeclientset:     kubeclientset,
+		esclientset:       esclientset,
+		options:           options,
+		namespaces:        watched,
+		clusterLister:     clusterLister{watched},
+		serviceLister:     serviceLister{watched},
+		statefulSetLister: statefulSetLister{watched},
+		configMapLister:   configMapLister{watched},
+		podLister:         podLister{watched},
+		newESClient:       newESClient,
+		queue:             queue,
+		recorder:          recorder,
+	}
 
-	go kubeInformerFactory.Start(ctx.Done())
-	go esInformerFactory.Start(ctx.Done())
+	if err := prometheus.Register(metricsCollector{controller}); err != nil {
+		logger.Errorf("Failed to register metrics: %v", err)
+	}
 
 	return controller
 }
 
+// handleObject queues the cluster owning a resource. Resources created by
+// the statefulsets of a cluster, such as pods and persistent volume claims,
+// are not owned by it and are matched through their cluster label instead.
 func (c *Controller) handleObject(obj interface{}) {
 	var object metav1.Object
 	var ok bool
@@ -168,28 +161,62 @@ func (c *Controller) handleObject(obj interface{}) {
 		object, ok = tombstone.Obj.(metav1.Object)
 		if !ok {
 			runtime.HandleError(fmt.Errorf("error decoding object tombstone, invalid type"))
-		}
-		c.Infof("Recovered deleted object '%s' from tombstone", object.GetName)
-	}
-	c.Infof("Processing object: %s", object.GetName())
-	if ownerRef := metav1.GetControllerOf(object); ownerRef != nil {
-		if ownerRef.Kind != "Cluster" {
 			return
 		}
+		c.Infof("Recovered deleted object '%s' from tombstone", object.GetName())
+	}
+	c.Debugf("Processing object: %s", object.GetName())
+
+	var clusterName string
+	if ownerRef := metav1.GetControllerOf(object); ownerRef != nil && ownerRef.Kind == "Cluster" {
+		clusterName = ownerRef.Name
+	} else if name, ok := object.GetLabels()[clusterLabel]; ok {
+		clusterName = name
+	} else {
+		return
+	}
 
-		cluster, err := c.clusterLister.Clusters(object.GetNamespace()).Get(ownerRef.Name)
-		if err != nil {
-			c.Infof("Ignoring orphaned object '%s' of cluster '%s'", object.GetSelfLink(), ownerRef.Name)
-			return
-		}
+	cluster, err := c.clusterLister.Clusters(object.GetNamespace()).Get(clusterName)
+	if err != nil {
+		c.Debugf("Ignoring orphaned object '%s' of cluster '%s'", object.GetSelfLink(), clusterName)
+		return
+	}
 
-		var key string
+	key, err := cache.MetaNamespaceKeyFunc(cluster)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
 
-		if key, err = cache.MetaNamespaceKeyFunc(cluster); err != nil {
-			runtime.HandleError(err)
-			return
+// handleSecret queues the clusters connecting to elasticsearch with a
+// secret, so changed credentials or certificate authorities are picked up,
+// as well as any cluster owning it
+func (c *Controller) handleSecret(obj interface{}) {
+	c.handleObject(obj)
+
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return
+	}
+
+	clusters, err := c.clusterLister.Clusters(secret.Namespace).List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, cluster := range clusters {
+		connection := cluster.Spec.Connection
+		if connection == nil || (connection.CredentialsSecret != secret.Name && connection.CASecret != secret.Name) {
+			continue
+		}
+		if key, err := cache.MetaNamespaceKeyFunc(cluster); err == nil {
+			c.queue.Add(key)
 		}
-		c.queue.AddRateLimited(key)
 	}
 }
 
@@ -219,8 +246,16 @@ func (c *Controller) processNextItem() bool {
 			return nil
 		}
 
-		if err := c.sync(key); err != nil {
-			return fmt.Errorf("error syncing '%s': %s", key, err.Error())
+		atomic.StoreInt64(&c.syncStarted, time.Now().UnixNano())
+		err := c.sync(key)
+		atomic.StoreInt64(&c.syncStarted, 0)
+		if err != nil {
+			if c.queue.NumRequeues(key) < maxRetries {
+				c.queue.AddRateLimited(key)
+				return fmt.Errorf("error syncing '%s', requeuing: %s", key, err.Error())
+			}
+			c.queue.Forget(obj)
+			return fmt.Errorf("error syncing '%s', giving up: %s", key, err.Error())
 		}
 
 		c.queue.Forget(obj)
@@ -235,77 +270,293 @@ func (c *Controller) processNextItem() bool {
 }
 
 func (c *Controller) sync(key string) error {
-	c.Infof("Processing change to %s", key)
-
 	namespace, name, err := cache.SplitMetaNamespaceKey(key)
 	if err != nil {
 		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
 		return nil
 	}
 
+	// the logger of the sync adds the cluster being synced to every line
+	logger := c.Logger.With("cluster", name, "namespace", namespace, "reconcileID", uuid.NewUUID())
+
+	start := time.Now()
+	gone, err := c.syncKey(logger, key, namespace, name)
+	// the metrics of a cluster that is gone have been forgotten, and are not
+	// recorded again
+	if !gone {
+		observeReconcile(namespace, name, time.Since(start), err)
+	}
+	return err
+}
+
+// syncKey syncs the cluster of key, returning whether the cluster is gone,
+// either deleted or released by removing its finalizer
+func (c *Controller) syncKey(logger log.Logger, key, namespace, name string) (bool, error) {
+	logger.Infof("Processing change to %s", key)
+
+	// the namespace may have stopped matching the namespace selector, or
+	// have been added by it and still be syncing
+	if c.namespaces.get(namespace) == nil {
+		return false, nil
+	}
+	if !c.namespaces.hasSynced(namespace) {
+		c.queue.AddAfter(key, time.Second)
+		return false, nil
+	}
+
 	cluster, err := c.clusterLister.Clusters(namespace).Get(name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			runtime.HandleError(fmt.Errorf("foo '%s' in work queue no longer exists", key))
-			return nil
+			return true, nil
+		}
+		return false, err
+	}
+
+	logger.Debugf("Object: %#v", cluster)
+
+	if cluster.DeletionTimestamp != nil {
+		return c.syncDeletion(logger, key, cluster)
+	}
+
+	if !hasFinalizer(cluster) {
+		return false, c.addFinalizer(cluster)
+	}
+
+	observed := &observedState{
+		statefulSets:           map[string]*v1beta2.StatefulSet{},
+		bootstrapped:           cluster.Status.Bootstrapped,
+		votingConfigExclusions: cluster.Status.VotingConfigExclusions,
+		upgrade:                cluster.Status.Upgrade.DeepCopy(),
+		excludedNodes:          cluster.Status.ExcludedNodes,
+	}
+	err = c.syncCluster(logger, cluster, observed)
+
+	if observed.requeue {
+		c.queue.AddAfter(key, requeueInterval)
+	}
+
+	if statusErr := c.updateStatus(cluster, newClusterStatus(cluster, observed, err)); statusErr != nil {
+		if err != nil {
+			runtime.HandleError(statusErr)
+			return false, err
+		}
+		return false, statusErr
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	msg := fmt.Sprintf(MessageResourceSynced, cluster.Name)
+	c.recorder.Event(cluster, corev1.EventTypeNormal, SuccessSynced, msg)
+
+	return false, nil
+}
+
+// syncCluster creates the child resources of a cluster, recording what it
+// observes of them for the status update
+func (c *Controller) syncCluster(logger log.Logger, cluster *esV1.Cluster, observed *observedState) error {
+	pools := cluster.Spec.DefaultedNodePools()
+	if err := validation.ValidateClusterSpec(&cluster.Spec).ToAggregate(); err != nil {
+		// retrying cannot help until the spec changes, so report it through
+		// the status instead of failing the sync
+		c.recorder.Event(cluster, corev1.EventTypeWarning, ErrInvalidSpec, err.Error())
+		observed.invalidSpec = err
+		return nil
+	}
+
+	v, err := clusterVersion(cluster)
+	if err == nil {
+		err = v.checkSupported()
+	}
+	if err != nil {
+		// retrying cannot help until the spec changes, so report the version
+		// through the status instead of failing the sync
+		c.recorder.Event(cluster, corev1.EventTypeWarning, ErrUnsupportedVersion, err.Error())
+		observed.unsupportedVersion = err
+		return nil
+	}
+
+	if err := c.removeLegacyDeployment(logger, cluster); err != nil {
+		return err
+	}
+
+	if v.usesZen2() && !observed.bootstrapped {
+		if err := c.syncBootstrapConfigMap(logger, cluster, pools); err != nil {
+			return err
+		}
+	}
+
+	logger.Infof("create master discovery service...")
+	desiredMasterService := newMasterService(cluster)
+	masterService, err := c.serviceLister.Services(cluster.Namespace).Get(desiredMasterService.Name)
+	if errors.IsNotFound(err) {
+		masterService, err = c.kubeclientset.CoreV1().Services(cluster.Namespace).Create(desiredMasterService)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := c.checkControlledBy(cluster, masterService); err != nil {
+		return err
+	}
+
+	if masterService, err = c.reconcileService(logger, cluster, desiredMasterService, masterService); err != nil {
+		return err
+	}
+
+	if err := c.syncHTTPService(logger, cluster, observed); err != nil {
+		return err
+	}
+
+	// the master count is taken before any pool is held back from scaling
+	// down, as held back nodes are about to leave the cluster
+	masters := validation.MasterNodes(pools)
+	if pools, err = c.syncScaleDown(logger, cluster, pools, observed); err != nil {
+		return err
+	}
+
+	for i := range pools {
+		if err := c.syncNodePool(logger, cluster, &pools[i], v, masterService.Name, masters, observed); err != nil {
+			return err
 		}
+	}
+
+	c.observeHealth(logger, cluster, observed)
+
+	if err := c.finishScaleDown(logger, cluster, observed); err != nil {
 		return err
 	}
 
-	c.Infof("Object: %#v", cluster)
+	if v.usesZen2() {
+		if err := c.syncVotingConfig(logger, cluster, observed); err != nil {
+			return err
+		}
+	}
+
+	return c.syncUpgrade(logger, cluster, pools, v, observed)
+}
 
-	c.Infof("create master discovery service...")
-	masterServiceName := fmt.Sprintf("%v-master-service", cluster.Name)
-	masterService, err := c.serviceLister.Services(cluster.Namespace).Get(masterServiceName)
+// syncNodePool creates the headless service, config map and statefulset of a
+// node pool
+func (c *Controller) syncNodePool(logger log.Logger, cluster *esV1.Cluster, pool *esV1.NodePool, v version, masterServiceURL string, masterNodes int32, observed *observedState) error {
+	logger.Infof("create %s node service...", pool.Name)
+	desiredService := newPoolService(cluster, pool)
+	service, err := c.serviceLister.Services(cluster.Namespace).Get(desiredService.Name)
 	if errors.IsNotFound(err) {
-		masterService, err = c.kubeclientset.CoreV1().Services(cluster.Namespace).Create(newMasterService(cluster))
+		service, err = c.kubeclientset.CoreV1().Services(cluster.Namespace).Create(desiredService)
 	}
 
 	if err != nil {
 		return err
 	}
 
-	if !metav1.IsControlledBy(masterService, cluster) {
-		msg := fmt.Sprintf(MessageResourceExists, masterService.Name)
-		c.recorder.Event(cluster, corev1.EventTypeWarning, ErrResourceExists, msg)
-		return fmt.Errorf(msg)
+	if err := c.checkControlledBy(cluster, service); err != nil {
+		return err
+	}
+
+	if _, err := c.reconcileService(logger, cluster, desiredService, service); err != nil {
+		return err
 	}
 
-	c.Infof("Creating master node deployment...")
-	masterDeploymentName := fmt.Sprintf("%v-master-deployment", cluster.Name)
-	masterDeployment, err := c.deploymentLister.Deployments(cluster.Namespace).Get(masterDeploymentName)
+	desiredConfigMap, err := newPoolConfigMap(cluster, pool)
+	if err != nil {
+		return err
+	}
+	configMap, err := c.syncConfigMap(logger, cluster, desiredConfigMap)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Creating %s node statefulset...", pool.Name)
+	desiredStatefulSet, err := newNodeStatefulSet(cluster, pool, v, masterServiceURL, masterNodes, configMap)
+	if err != nil {
+		return err
+	}
+	statefulSet, err := c.statefulSetLister.StatefulSets(cluster.Namespace).Get(desiredStatefulSet.Name)
 	if errors.IsNotFound(err) {
-		masterDeployment, err = c.kubeclientset.AppsV1beta2().Deployments(cluster.Namespace).Create(newMasterDeployment(cluster, masterServiceName))
+		statefulSet, err = c.kubeclientset.AppsV1beta2().StatefulSets(cluster.Namespace).Create(desiredStatefulSet)
 	}
 
 	if err != nil {
 		return err
 	}
 
-	if !metav1.IsControlledBy(masterDeployment, cluster) {
-		msg := fmt.Sprintf(MessageResourceExists, masterDeployment.Name)
-		c.recorder.Event(cluster, corev1.EventTypeWarning, ErrResourceExists, msg)
-		return fmt.Errorf(msg)
+	if err := c.checkControlledBy(cluster, statefulSet); err != nil {
+		return err
 	}
 
-	msg := fmt.Sprintf(MessageResourceSynced, cluster.Name)
-	c.recorder.Event(cluster, corev1.EventTypeNormal, SuccessSynced, msg)
+	if v.usesZen2() && pool.HasRole(esV1.NodeRoleMaster) {
+		if err := c.excludeRemovedMasters(logger, cluster, pool, v, statefulSet, observed); err != nil {
+			return err
+		}
+	}
 
+	if statefulSet, err = c.reconcileStatefulSet(logger, cluster, desiredStatefulSet, statefulSet); err != nil {
+		return err
+	}
+
+	observed.observeStatefulSet(pool, statefulSet)
+
+	return nil
+}
+
+// checkControlledBy records a warning and returns an error if object is not
+// managed by cluster
+func (c *Controller) checkControlledBy(cluster *esV1.Cluster, object metav1.Object) error {
+	if !metav1.IsControlledBy(object, cluster) {
+		msg := fmt.Sprintf(MessageResourceExists, object.GetName())
+		c.recorder.Event(cluster, corev1.EventTypeWarning, ErrResourceExists, msg)
+		return fmt.Errorf("%s", msg)
+	}
 	return nil
 }
 
-func (c *Controller) Run(ctx context.Context) {
+// Run processes clusters until ctx is cancelled. It returns an error if the
+// informers fail to sync.
+func (c *Controller) Run(ctx context.Context) error {
 	defer utilruntime.HandleCrash()
 	defer c.queue.ShutDown()
 
 	c.Infof("Starting Controller...")
 
-	if !cache.WaitForCacheSync(ctx.Done(), c.clustersSynced, c.servicesSynced) {
-		utilruntime.HandleError(fmt.Errorf("Timed out waiting for cache to sync"))
-		return
+	defer c.namespaces.removeAll()
+
+	synced := []cache.InformerSynced{}
+	if c.options.allNamespaces() {
+		c.watchNamespace(metav1.NamespaceAll)
+	}
+	for _, namespace := range c.options.Namespaces {
+		c.watchNamespace(namespace)
+	}
+	if c.options.NamespaceSelector != nil {
+		synced = append(synced, c.runNamespaceSelector(ctx.Done()))
+	}
+	synced = append(synced, c.namespaces.allSynced)
+
+	atomic.StoreInt32(&c.running, 1)
+	defer atomic.StoreInt32(&c.running, 0)
+
+	syncCtx, cancelSync := context.WithTimeout(ctx, cacheSyncTimeout)
+	defer cancelSync()
+	if !cache.WaitForCacheSync(syncCtx.Done(), synced...) {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("timed out waiting for caches to sync")
 	}
 
 	c.Infof("Controller started")
 
+	// shutting down the queue lets the worker return once the items already
+	// queued have been processed
+	go func() {
+		<-ctx.Done()
+		c.queue.ShutDown()
+	}()
+
 	wait.Until(c.runWorker, time.Second, ctx.Done())
+	return nil
 }
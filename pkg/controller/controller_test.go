@@ -0,0 +1,325 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	esfake "github.com/matt-tyler/elasticsearch-operator/pkg/client/clientset/versioned/fake"
+	listers "github.com/matt-tyler/elasticsearch-operator/pkg/client/listers/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/esclient"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/esclient/fake"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	v1beta2 "k8s.io/api/apps/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	appslisters "k8s.io/client-go/listers/apps/v1beta2"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const testNamespace = "default"
+
+// fixture runs the controller against fake clientsets and a fake
+// elasticsearch cluster. Its listers are refilled from the clientsets before
+// every sync, standing in for the informers.
+type fixture struct {
+	t *testing.T
+
+	kubeclient *kubefake.Clientset
+	esclient   *esfake.Clientset
+	server     *fake.Server
+	controller *Controller
+
+	clusters     cache.Indexer
+	services     cache.Indexer
+	statefulSets cache.Indexer
+	configMaps   cache.Indexer
+	pods         cache.Indexer
+}
+
+func newFixture(t *testing.T, cluster *esV1.Cluster, objects ...runtime.Object) *fixture {
+	f := &fixture{
+		t:            t,
+		kubeclient:   kubefake.NewSimpleClientset(objects...),
+		esclient:     esfake.NewSimpleClientset(cluster),
+		server:       fake.NewServer(),
+		clusters:     emptyIndexer(),
+		services:     emptyIndexer(),
+		statefulSets: emptyIndexer(),
+		configMaps:   emptyIndexer(),
+		pods:         emptyIndexer(),
+	}
+
+	watched := &namespaces{informers: map[string]*namespaceInformers{
+		testNamespace: {
+			namespace:         testNamespace,
+			stop:              make(chan struct{}),
+			clusterLister:     listers.NewClusterLister(f.clusters),
+			serviceLister:     corelisters.NewServiceLister(f.services),
+			statefulSetLister: appslisters.NewStatefulSetLister(f.statefulSets),
+			configMapLister:   corelisters.NewConfigMapLister(f.configMaps),
+			podLister:         corelisters.NewPodLister(f.pods),
+		},
+	}}
+
+	f.controller = &Controller{
+		Logger:            log.NewLogger(),
+		kubeclientset:     f.kubeclient,
+		esclientset:       f.esclient,
+		namespaces:        watched,
+		clusterLister:     clusterLister{watched},
+		serviceLister:     serviceLister{watched},
+		statefulSetLister: statefulSetLister{watched},
+		configMapLister:   configMapLister{watched},
+		podLister:         podLister{watched},
+		newESClient: func(esclient.Config) (esclient.Interface, error) {
+			return f.server.Client(), nil
+		},
+		queue:    workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		recorder: record.NewFakeRecorder(1000),
+	}
+	return f
+}
+
+func (f *fixture) close() {
+	f.controller.queue.ShutDown()
+	f.server.Close()
+}
+
+// newTestCluster returns a cluster with the default pools of a master node
+// and two data nodes
+func newTestCluster() *esV1.Cluster {
+	cluster := &esV1.Cluster{}
+	cluster.Name = "test"
+	cluster.Namespace = testNamespace
+	cluster.UID = "test-uid"
+	cluster.Finalizers = []string{clusterFinalizer}
+	cluster.Spec = esV1.ClusterSpec{
+		Version: "6.4.2",
+		Size:    2,
+		Storage: esV1.StorageSpec{Size: resource.MustParse("10Gi")},
+	}
+	return cluster
+}
+
+// refresh replaces the contents of the listers with the objects held by the
+// clientsets
+func (f *fixture) refresh() {
+	clusters, err := f.esclient.EsV1().Clusters(testNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	var items []interface{}
+	for i := range clusters.Items {
+		items = append(items, &clusters.Items[i])
+	}
+	f.clusters.Replace(items, "")
+
+	kube := f.kubeclient
+	services, err := kube.CoreV1().Services(testNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	items = nil
+	for i := range services.Items {
+		items = append(items, &services.Items[i])
+	}
+	f.services.Replace(items, "")
+
+	statefulSets, err := kube.AppsV1beta2().StatefulSets(testNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	items = nil
+	for i := range statefulSets.Items {
+		items = append(items, &statefulSets.Items[i])
+	}
+	f.statefulSets.Replace(items, "")
+
+	configMaps, err := kube.CoreV1().ConfigMaps(testNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	items = nil
+	for i := range configMaps.Items {
+		items = append(items, &configMaps.Items[i])
+	}
+	f.configMaps.Replace(items, "")
+
+	pods, err := kube.CoreV1().Pods(testNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	items = nil
+	for i := range pods.Items {
+		items = append(items, &pods.Items[i])
+	}
+	f.pods.Replace(items, "")
+}
+
+// sync runs a single sync of the test cluster
+func (f *fixture) sync() error {
+	f.refresh()
+	return f.controller.sync(testNamespace + "/test")
+}
+
+func (f *fixture) mustSync() {
+	if err := f.sync(); err != nil {
+		f.t.Fatalf("sync failed: %v", err)
+	}
+}
+
+func (f *fixture) cluster() *esV1.Cluster {
+	cluster, err := f.esclient.EsV1().Clusters(testNamespace).Get("test", metav1.GetOptions{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return cluster
+}
+
+func (f *fixture) updateCluster(update func(*esV1.Cluster)) {
+	cluster := f.cluster()
+	update(cluster)
+	if _, err := f.esclient.EsV1().Clusters(testNamespace).Update(cluster); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+func (f *fixture) statefulSet(name string) *v1beta2.StatefulSet {
+	statefulSet, err := f.kubeclient.AppsV1beta2().StatefulSets(testNamespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return statefulSet
+}
+
+// rollOut marks every replica of a statefulset ready at revision
+func (f *fixture) rollOut(name, revision string) {
+	statefulSet := f.statefulSet(name)
+	replicas := *statefulSet.Spec.Replicas
+	statefulSet.Status = v1beta2.StatefulSetStatus{
+		ObservedGeneration: statefulSet.Generation,
+		Replicas:           replicas,
+		ReadyReplicas:      replicas,
+		UpdatedReplicas:    replicas,
+		UpdateRevision:     revision,
+	}
+	if _, err := f.kubeclient.AppsV1beta2().StatefulSets(testNamespace).Update(statefulSet); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// createPods creates the pods of a statefulset at revision and adds their
+// nodes to the fake cluster
+func (f *fixture) createPods(name, revision string, ready bool) {
+	statefulSet := f.statefulSet(name)
+	for i := int32(0); i < *statefulSet.Spec.Replicas; i++ {
+		f.createPod(statefulSet, fmt.Sprintf("%s-%d", name, i), revision, ready)
+	}
+}
+
+func (f *fixture) createPod(statefulSet *v1beta2.StatefulSet, name, revision string, ready bool) {
+	labels := map[string]string{v1beta2.StatefulSetRevisionLabel: revision}
+	for k, v := range statefulSet.Spec.Template.Labels {
+		labels[k] = v
+	}
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+			Labels:    labels,
+		},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+	if _, err := f.kubeclient.CoreV1().Pods(testNamespace).Create(pod); err != nil {
+		f.t.Fatal(err)
+	}
+
+	nodeName := f.nodeName(statefulSet, name)
+	f.server.Lock()
+	defer f.server.Unlock()
+	if !hasNode(f.server.Nodes, nodeName) {
+		f.server.Nodes = append(f.server.Nodes, esclient.Node{Name: nodeName})
+	}
+}
+
+// nodeName returns the name the node of a pod joins the cluster with, read
+// from the elasticsearch.yml of its statefulset. Unless configured, 6.x nodes
+// are named after a prefix of their random node id.
+func (f *fixture) nodeName(statefulSet *v1beta2.StatefulSet, pod string) string {
+	for _, volume := range statefulSet.Spec.Template.Spec.Volumes {
+		if volume.Name != configVolumeName || volume.ConfigMap == nil {
+			continue
+		}
+		configMap, err := f.kubeclient.CoreV1().ConfigMaps(testNamespace).Get(volume.ConfigMap.Name, metav1.GetOptions{})
+		if err != nil {
+			f.t.Fatal(err)
+		}
+		var config map[string]interface{}
+		if err := yaml.Unmarshal([]byte(configMap.Data[configKey]), &config); err != nil {
+			f.t.Fatal(err)
+		}
+		if config["node.name"] == "${HOSTNAME}" {
+			return pod
+		}
+	}
+	return fmt.Sprintf("%.7x", sha256.Sum256([]byte(pod)))
+}
+
+func (f *fixture) podExists(name string) bool {
+	_, err := f.kubeclient.CoreV1().Pods(testNamespace).Get(name, metav1.GetOptions{})
+	return err == nil
+}
+
+// persistentSetting returns a persistent cluster setting of the fake cluster
+func (f *fixture) persistentSetting(key string) interface{} {
+	f.server.Lock()
+	defer f.server.Unlock()
+	return f.server.Settings.Persistent[key]
+}
+
+func TestSyncInvalidSpec(t *testing.T) {
+	cluster := newTestCluster()
+	cluster.Spec.Size = -1
+	f := newFixture(t, cluster)
+	defer f.close()
+
+	if err := f.sync(); err != nil {
+		t.Fatalf("expected an invalid spec not retried, got %v", err)
+	}
+	condition := getCondition(&f.cluster().Status, esV1.ClusterDegradedCondition)
+	if condition == nil || condition.Status != corev1.ConditionTrue || condition.Reason != ReasonInvalidSpec {
+		t.Errorf("expected cluster degraded by its invalid spec, got %+v", condition)
+	}
+}
+
+// TestNodesNamedAfterPods checks 6.x nodes join the cluster under the name of
+// their pod, which the operator relies on to drain and restart them
+func TestNodesNamedAfterPods(t *testing.T) {
+	cluster := newTestCluster()
+	cluster.Spec.Version = "6.1.1"
+	f := newFixture(t, cluster)
+	defer f.close()
+	f.mustSync()
+	f.createPods("test-data", "rev", true)
+
+	for _, pod := range []string{"test-data-0", "test-data-1"} {
+		if !hasNode(f.server.Nodes, pod) {
+			t.Errorf("expected node named %s, got %+v", pod, f.server.Nodes)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/matt-tyler/elasticsearch-operator/pkg/apis/es"
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/esclient"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// clusterFinalizer holds back the removal of a cluster until its deletion
+// policy has been carried out
+const clusterFinalizer = es.GroupName + "/cleanup"
+
+const (
+	// SuccessDeleted is used as part of the Event 'reason' when the resources
+	// of a deleted cluster have been removed
+	SuccessDeleted = "Deleted"
+
+	// ErrSnapshotFailed is used as part of the Event 'reason' when the final
+	// snapshot of a deleted cluster could not be taken
+	ErrSnapshotFailed = "ErrSnapshotFailed"
+
+	// MessageResourcesDeleted is the message used for events when the
+	// resources of a deleted cluster have been removed
+	MessageResourcesDeleted = "Resources of %q deleted with policy %s"
+)
+
+func deletionPolicy(cluster *esV1.Cluster) esV1.DeletionPolicy {
+	if cluster.Spec.DeletionPolicy == "" {
+		return esV1.DeletionPolicyDelete
+	}
+	return cluster.Spec.DeletionPolicy
+}
+
+func hasFinalizer(cluster *esV1.Cluster) bool {
+	return containsString(cluster.Finalizers, clusterFinalizer)
+}
+
+// addFinalizer adds the cleanup finalizer to a cluster. The update is picked
+// up by the cluster informer, which syncs the cluster again.
+func (c *Controller) addFinalizer(cluster *esV1.Cluster) error {
+	updated := cluster.DeepCopy()
+	updated.Finalizers = append(updated.Finalizers, clusterFinalizer)
+	_, err := c.esclientset.EsV1().Clusters(cluster.Namespace).Update(updated)
+	return err
+}
+
+// removeFinalizer removes the cleanup finalizer from the latest version of a
+// cluster, as its status may have just been updated
+func (c *Controller) removeFinalizer(cluster *esV1.Cluster) error {
+	latest, err := c.esclientset.EsV1().Clusters(cluster.Namespace).Get(cluster.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	updated := latest.DeepCopy()
+	updated.Finalizers = nil
+	for _, finalizer := range latest.Finalizers {
+		if finalizer != clusterFinalizer {
+			updated.Finalizers = append(updated.Finalizers, finalizer)
+		}
+	}
+	_, err = c.esclientset.EsV1().Clusters(cluster.Namespace).Update(updated)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// syncDeletion carries out the deletion policy of a deleted cluster, removing
+// the finalizer and the metrics of the cluster once cleanup has succeeded.
+// Progress is reported through status.deletion. It returns whether the
+// cluster has been released.
+func (c *Controller) syncDeletion(logger log.Logger, key string, cluster *esV1.Cluster) (bool, error) {
+	if !hasFinalizer(cluster) {
+		return true, nil
+	}
+
+	policy := deletionPolicy(cluster)
+	status := *cluster.Status.DeepCopy()
+	status.Phase = esV1.ClusterTerminating
+	if status.Deletion == nil {
+		status.Deletion = &esV1.DeletionStatus{Phase: esV1.DeletionDeletingResources}
+		if policy == esV1.DeletionPolicySnapshot {
+			status.Deletion.Phase = esV1.DeletionSnapshotting
+		}
+	}
+	deletion := status.Deletion
+	deletion.Message = ""
+
+	// the policy may be changed from Snapshot to abandon the snapshot
+	if deletion.Phase == esV1.DeletionSnapshotting && policy != esV1.DeletionPolicySnapshot {
+		deletion.Phase = esV1.DeletionDeletingResources
+	}
+
+	if deletion.Phase == esV1.DeletionSnapshotting {
+		done, err := c.syncFinalSnapshot(logger, cluster, deletion)
+		if err != nil {
+			deletion.Message = err.Error()
+			c.recorder.Event(cluster, corev1.EventTypeWarning, ErrSnapshotFailed, err.Error())
+		}
+		if !done {
+			c.queue.AddAfter(key, requeueInterval)
+			return false, c.updateStatus(cluster, status)
+		}
+		deletion.Phase = esV1.DeletionDeletingResources
+	}
+
+	if err := c.updateStatus(cluster, status); err != nil {
+		return false, err
+	}
+
+	if err := c.deleteResources(cluster, policy != esV1.DeletionPolicyRetain); err != nil {
+		return false, err
+	}
+
+	msg := fmt.Sprintf(MessageResourcesDeleted, cluster.Name, policy)
+	c.recorder.Event(cluster, corev1.EventTypeNormal, SuccessDeleted, msg)
+	if err := c.removeFinalizer(cluster); err != nil {
+		return false, err
+	}
+	forgetClusterMetrics(cluster.Namespace, cluster.Name)
+	return true, nil
+}
+
+// syncFinalSnapshot starts the final snapshot of a cluster and returns
+// whether it has completed. Errors reaching the cluster are returned to be
+// reported, and the snapshot is retried.
+func (c *Controller) syncFinalSnapshot(logger log.Logger, cluster *esV1.Cluster, deletion *esV1.DeletionStatus) (bool, error) {
+	spec := cluster.Spec.Snapshot
+	if spec == nil || spec.Repository == "" {
+		return false, fmt.Errorf("deletion policy %s requires spec.snapshot.repository", esV1.DeletionPolicySnapshot)
+	}
+
+	client, err := c.esClient(cluster)
+	if err != nil {
+		return false, err
+	}
+
+	if deletion.Snapshot == "" {
+		if spec.Type != "" {
+			settings := map[string]interface{}{}
+			for k, v := range spec.Settings {
+				settings[k] = v
+			}
+			repository := esclient.SnapshotRepository{Type: spec.Type, Settings: settings}
+			if err := client.PutSnapshotRepository(spec.Repository, repository); err != nil {
+				return false, fmt.Errorf("failed to register snapshot repository %s: %v", spec.Repository, err)
+			}
+		}
+
+		name := fmt.Sprintf("%v-final-%d", cluster.Name, cluster.DeletionTimestamp.Unix())
+		logger.Infof("Taking final snapshot %s of cluster %s", name, cluster.Name)
+		if err := client.CreateSnapshot(spec.Repository, name); err != nil {
+			return false, fmt.Errorf("failed to create snapshot %s: %v", name, err)
+		}
+		deletion.Snapshot = name
+		return false, nil
+	}
+
+	snapshot, err := client.GetSnapshot(spec.Repository, deletion.Snapshot)
+	if err != nil {
+		return false, fmt.Errorf("failed to get snapshot %s: %v", deletion.Snapshot, err)
+	}
+
+	switch snapshot.State {
+	case esclient.SnapshotSuccess:
+		return true, nil
+	case esclient.SnapshotFailed, esclient.SnapshotPartial:
+		return false, fmt.Errorf("snapshot %s finished in state %s, set deletionPolicy to Delete to delete the cluster without it", snapshot.Snapshot, snapshot.State)
+	default:
+		return false, nil
+	}
+}
+
+// deleteResources removes the statefulsets, services and config maps
+// controlled by a cluster, and its persistent volume claims unless they are
+// retained. Claims are created by the statefulsets and carry the cluster
+// label rather than an owner reference.
+func (c *Controller) deleteResources(cluster *esV1.Cluster, deleteClaims bool) error {
+	propagation := metav1.DeletePropagationBackground
+	options := &metav1.DeleteOptions{PropagationPolicy: &propagation}
+
+	statefulSets, err := c.statefulSetLister.StatefulSets(cluster.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, statefulSet := range statefulSets {
+		if !metav1.IsControlledBy(statefulSet, cluster) {
+			continue
+		}
+		err := c.kubeclientset.AppsV1beta2().StatefulSets(cluster.Namespace).Delete(statefulSet.Name, options)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	services, err := c.serviceLister.Services(cluster.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, service := range services {
+		if !metav1.IsControlledBy(service, cluster) {
+			continue
+		}
+		err := c.kubeclientset.CoreV1().Services(cluster.Namespace).Delete(service.Name, options)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	configMaps, err := c.configMapLister.ConfigMaps(cluster.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, configMap := range configMaps {
+		if !metav1.IsControlledBy(configMap, cluster) {
+			continue
+		}
+		err := c.kubeclientset.CoreV1().ConfigMaps(cluster.Namespace).Delete(configMap.Name, options)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	if !deleteClaims {
+		return nil
+	}
+
+	selector := labels.Set{
+		clusterLabel: cluster.Name,
+		"operator":   "elasticsearch-operator",
+	}.AsSelector().String()
+	return c.kubeclientset.CoreV1().PersistentVolumeClaims(cluster.Namespace).DeleteCollection(options, metav1.ListOptions{
+		LabelSelector: selector,
+	})
+}
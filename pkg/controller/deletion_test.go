@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"testing"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/esclient"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	core "k8s.io/client-go/testing"
+)
+
+// newDeletedFixture returns a fixture whose cluster has been synced, then
+// deleted with policy
+func newDeletedFixture(t *testing.T, policy esV1.DeletionPolicy) *fixture {
+	cluster := newTestCluster()
+	cluster.Spec.DeletionPolicy = policy
+	cluster.Spec.Snapshot = &esV1.SnapshotSpec{
+		Repository: "backups",
+		Type:       "fs",
+		Settings:   map[string]string{"location": "/backups"},
+	}
+	f := newFixture(t, cluster)
+	f.mustSync()
+
+	deleted := metav1.Now()
+	f.updateCluster(func(cluster *esV1.Cluster) {
+		cluster.DeletionTimestamp = &deleted
+	})
+	return f
+}
+
+// deletedClaims returns whether the persistent volume claims of the cluster
+// have been deleted
+func (f *fixture) deletedClaims() bool {
+	for _, action := range f.kubeclient.Actions() {
+		if action.Matches("delete-collection", "persistentvolumeclaims") {
+			selector := action.(core.DeleteCollectionAction).GetListRestrictions().Labels
+			return selector.Matches(labels.Set{clusterLabel: "test", "operator": "elasticsearch-operator"})
+		}
+	}
+	return false
+}
+
+func (f *fixture) resourcesDeleted() bool {
+	statefulSets, err := f.kubeclient.AppsV1beta2().StatefulSets(testNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	services, err := f.kubeclient.CoreV1().Services(testNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	configMaps, err := f.kubeclient.CoreV1().ConfigMaps(testNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return len(statefulSets.Items) == 0 && len(services.Items) == 0 && len(configMaps.Items) == 0
+}
+
+func TestDeletionPolicies(t *testing.T) {
+	tests := []struct {
+		policy esV1.DeletionPolicy
+		// syncs is the number of syncs needed to carry out the policy
+		syncs int
+
+		expectedClaimsDeleted bool
+		expectedSnapshot      bool
+	}{
+		{esV1.DeletionPolicyDelete, 1, true, false},
+		{esV1.DeletionPolicyRetain, 1, false, false},
+		{esV1.DeletionPolicySnapshot, 2, true, true},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.policy), func(t *testing.T) {
+			f := newDeletedFixture(t, test.policy)
+			defer f.close()
+			f.server.Indices = []esclient.Index{{Index: "logs"}}
+
+			for i := 0; i < test.syncs; i++ {
+				if !hasFinalizer(f.cluster()) {
+					t.Fatalf("expected finalizer kept until sync %d", test.syncs)
+				}
+				f.mustSync()
+			}
+
+			if hasFinalizer(f.cluster()) {
+				t.Errorf("expected finalizer removed")
+			}
+			if !f.resourcesDeleted() {
+				t.Errorf("expected statefulsets, services and config maps deleted")
+			}
+			if deleted := f.deletedClaims(); deleted != test.expectedClaimsDeleted {
+				t.Errorf("expected claims deleted %v, got %v", test.expectedClaimsDeleted, deleted)
+			}
+			if snapshots := len(f.server.Snapshots["backups"]); (snapshots == 1) != test.expectedSnapshot {
+				t.Errorf("expected snapshot taken %v, got %d snapshots", test.expectedSnapshot, snapshots)
+			}
+			if reconcileDuration.DeleteLabelValues(testNamespace, "test") {
+				t.Errorf("expected reconcile metrics of the deleted cluster removed")
+			}
+		})
+	}
+}
+
+func TestDeletionSnapshotFailed(t *testing.T) {
+	f := newDeletedFixture(t, esV1.DeletionPolicySnapshot)
+	defer f.close()
+	f.server.SnapshotState = esclient.SnapshotFailed
+
+	f.mustSync()
+	f.mustSync()
+
+	cluster := f.cluster()
+	if !hasFinalizer(cluster) || f.resourcesDeleted() {
+		t.Fatalf("expected cluster kept after a failed snapshot")
+	}
+	deletion := cluster.Status.Deletion
+	if deletion == nil || deletion.Phase != esV1.DeletionSnapshotting || deletion.Message == "" {
+		t.Fatalf("expected failed snapshot reported, got %+v", deletion)
+	}
+
+	// abandons the snapshot
+	f.updateCluster(func(cluster *esV1.Cluster) {
+		cluster.Spec.DeletionPolicy = esV1.DeletionPolicyDelete
+	})
+	f.mustSync()
+	if hasFinalizer(f.cluster()) || !f.resourcesDeleted() {
+		t.Errorf("expected cluster deleted once the snapshot is abandoned")
+	}
+}
@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"fmt"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/esclient"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	v1beta2 "k8s.io/api/apps/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	usernameKey = "username"
+	passwordKey = "password"
+	caCertKey   = "ca.crt"
+)
+
+// newESClient is the default constructor of elasticsearch clients
+func newESClient(config esclient.Config) (esclient.Interface, error) {
+	return esclient.New(config)
+}
+
+// esClient returns a client for the REST api of the cluster, reached through
+// the master discovery service with the credentials and certificate
+// authority named by spec.connection
+func (c *Controller) esClient(cluster *esV1.Cluster) (esclient.Interface, error) {
+	config := esclient.Config{
+		URL: esclient.ServiceURL("http", masterServiceName(cluster), cluster.Namespace, 9200),
+	}
+
+	connection := cluster.Spec.Connection
+	if connection == nil {
+		return c.newESClient(config)
+	}
+
+	if connection.Scheme != "" {
+		config.URL = esclient.ServiceURL(connection.Scheme, masterServiceName(cluster), cluster.Namespace, 9200)
+	}
+	config.InsecureSkipVerify = connection.InsecureSkipVerify
+
+	if connection.CredentialsSecret != "" {
+		secret, err := c.kubeclientset.CoreV1().Secrets(cluster.Namespace).Get(connection.CredentialsSecret, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get credentials of cluster %s: %v", cluster.Name, err)
+		}
+		config.Username = string(secret.Data[usernameKey])
+		config.Password = string(secret.Data[passwordKey])
+	}
+
+	if connection.CASecret != "" {
+		secret, err := c.kubeclientset.CoreV1().Secrets(cluster.Namespace).Get(connection.CASecret, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get certificate authority of cluster %s: %v", cluster.Name, err)
+		}
+		if len(secret.Data[caCertKey]) == 0 {
+			return nil, fmt.Errorf("secret %s has no %s key", secret.Name, caCertKey)
+		}
+		config.CACert = secret.Data[caCertKey]
+	}
+
+	return c.newESClient(config)
+}
+
+// observeHealth records the health of the cluster once any of its nodes are
+// ready. A cluster answering the health api has elected a master, so this is
+// also when a zen2 cluster is considered bootstrapped.
+func (c *Controller) observeHealth(logger log.Logger, cluster *esV1.Cluster, observed *observedState) {
+	observed.health = esV1.ClusterHealthUnknown
+	if !observed.anyReady() {
+		return
+	}
+
+	client, err := c.esClient(cluster)
+	if err != nil {
+		logger.Infof("Cannot reach cluster %s: %v", cluster.Name, err)
+		return
+	}
+
+	health, err := client.Health()
+	if err != nil {
+		logger.Infof("Failed to get health of cluster %s: %v", cluster.Name, err)
+		return
+	}
+
+	observed.health = esV1.ClusterHealth(health.Status)
+	observed.bootstrapped = true
+}
+
+// syncBootstrapConfigMap publishes the initial master nodes of a zen2 cluster
+// that has not yet formed. It must exist before the nodes start, as they only
+// read it on startup.
+func (c *Controller) syncBootstrapConfigMap(logger log.Logger, cluster *esV1.Cluster, pools []esV1.NodePool) error {
+	_, err := c.syncConfigMap(logger, cluster, newBootstrapConfigMap(cluster, initialMasterNodes(cluster, pools)))
+	return err
+}
+
+// syncConfigMap creates the config map desired, or updates the data of the
+// existing config map
+func (c *Controller) syncConfigMap(logger log.Logger, cluster *esV1.Cluster, desired *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	configMap, err := c.configMapLister.ConfigMaps(cluster.Namespace).Get(desired.Name)
+	if errors.IsNotFound(err) {
+		configMap, err = c.kubeclientset.CoreV1().ConfigMaps(cluster.Namespace).Create(desired)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.checkControlledBy(cluster, configMap); err != nil {
+		return nil, err
+	}
+
+	if equality.Semantic.DeepEqual(desired.Data, configMap.Data) {
+		return configMap, nil
+	}
+
+	updated := configMap.DeepCopy()
+	updated.Data = desired.Data
+	if configMap, err = c.kubeclientset.CoreV1().ConfigMaps(cluster.Namespace).Update(updated); err != nil {
+		return nil, err
+	}
+	c.recordUpdate(logger, cluster, configMap.Name, []string{"data"})
+	return configMap, nil
+}
+
+// removeBootstrapConfigMap deletes the initial master nodes of a cluster that
+// has formed, so nodes started from now on join the existing cluster
+func (c *Controller) removeBootstrapConfigMap(logger log.Logger, cluster *esV1.Cluster) error {
+	configMap, err := c.configMapLister.ConfigMaps(cluster.Namespace).Get(bootstrapConfigMapName(cluster))
+	if errors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !metav1.IsControlledBy(configMap, cluster) {
+		return nil
+	}
+
+	logger.Infof("Cluster %s has bootstrapped, removing initial master nodes", cluster.Name)
+	err = c.kubeclientset.CoreV1().ConfigMaps(cluster.Namespace).Delete(configMap.Name, nil)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// excludeRemovedMasters excludes the master nodes about to be removed by
+// scaling down a pool from the voting configuration, so the remaining masters
+// keep a quorum
+func (c *Controller) excludeRemovedMasters(logger log.Logger, cluster *esV1.Cluster, pool *esV1.NodePool, v version, statefulSet *v1beta2.StatefulSet, observed *observedState) error {
+	if statefulSet.Spec.Replicas == nil || *statefulSet.Spec.Replicas <= *pool.Replicas {
+		return nil
+	}
+
+	removed := nodeNames(cluster, pool, *pool.Replicas, *statefulSet.Spec.Replicas)
+	logger.Infof("Excluding master nodes %v of cluster %s from voting", removed, cluster.Name)
+	client, err := c.esClient(cluster)
+	if err != nil {
+		return err
+	}
+	if err := client.AddVotingConfigExclusions(v.Major, v.Minor, removed); err != nil {
+		return err
+	}
+
+	for _, name := range removed {
+		if !containsString(observed.votingConfigExclusions, name) {
+			observed.votingConfigExclusions = append(observed.votingConfigExclusions, name)
+		}
+	}
+	return nil
+}
+
+// syncVotingConfig removes the bootstrap config map of a formed cluster and
+// clears voting exclusions once the excluded masters have been removed
+func (c *Controller) syncVotingConfig(logger log.Logger, cluster *esV1.Cluster, observed *observedState) error {
+	if observed.bootstrapped {
+		if err := c.removeBootstrapConfigMap(logger, cluster); err != nil {
+			return err
+		}
+	}
+
+	if len(observed.votingConfigExclusions) == 0 || observed.mastersScalingDown {
+		return nil
+	}
+
+	logger.Infof("Clearing voting config exclusions of cluster %s", cluster.Name)
+	client, err := c.esClient(cluster)
+	if err != nil {
+		return err
+	}
+	if err := client.ClearVotingConfigExclusions(); err != nil {
+		return err
+	}
+	observed.votingConfigExclusions = nil
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// maxSyncDuration is how long a single sync may take before the worker is
+// considered stuck
+const maxSyncDuration = 5 * time.Minute
+
+// Healthz returns an error when the worker has been stuck syncing a cluster
+// for longer than maxSyncDuration, which a restart of the process may clear
+func (c *Controller) Healthz() error {
+	started := atomic.LoadInt64(&c.syncStarted)
+	if started == 0 {
+		return nil
+	}
+	if elapsed := time.Since(time.Unix(0, started)); elapsed > maxSyncDuration {
+		return fmt.Errorf("worker has been syncing a cluster for %v", elapsed.Round(time.Second))
+	}
+	return nil
+}
+
+// Readyz returns an error until the controller is running with the informers
+// of every watched namespace synced
+func (c *Controller) Readyz() error {
+	if atomic.LoadInt32(&c.running) == 0 {
+		return fmt.Errorf("controller is not running")
+	}
+	for _, informers := range c.namespaces.all() {
+		if !informers.hasSynced() {
+			if informers.namespace == "" {
+				return fmt.Errorf("informers have not synced")
+			}
+			return fmt.Errorf("informers of namespace %s have not synced", informers.namespace)
+		}
+	}
+	return nil
+}
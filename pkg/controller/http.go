@@ -0,0 +1,32 @@
+package controller
+
+import (
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// syncHTTPService creates the service clients use to reach the data and
+// coordinating nodes of the cluster, and records its endpoint
+func (c *Controller) syncHTTPService(logger log.Logger, cluster *esV1.Cluster, observed *observedState) error {
+	desired := newHTTPService(cluster)
+	service, err := c.serviceLister.Services(cluster.Namespace).Get(desired.Name)
+	if errors.IsNotFound(err) {
+		service, err = c.kubeclientset.CoreV1().Services(cluster.Namespace).Create(desired)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := c.checkControlledBy(cluster, service); err != nil {
+		return err
+	}
+
+	if service, err = c.reconcileService(logger, cluster, desired, service); err != nil {
+		return err
+	}
+
+	observed.endpoint = httpEndpoint(cluster, service)
+	return nil
+}
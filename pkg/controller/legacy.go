@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"fmt"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// legacyDeploymentName is the name of the deployment that ran the single
+// master node of clusters created before node pools ran as statefulsets
+func legacyDeploymentName(cluster *esV1.Cluster) string {
+	return fmt.Sprintf("%v-master-deployment", cluster.Name)
+}
+
+// removeLegacyDeployment deletes the master deployment of a cluster created by
+// an older operator, whose nodes are replaced by those of the node pools. The
+// master discovery service of these clusters kept its name and is reconciled
+// like any other service. The older operator wrote no status, so clusters are
+// only checked until their status is first written.
+func (c *Controller) removeLegacyDeployment(logger log.Logger, cluster *esV1.Cluster) error {
+	if cluster.Status.ObservedGeneration != 0 {
+		return nil
+	}
+
+	deployments := c.kubeclientset.AppsV1beta2().Deployments(cluster.Namespace)
+	deployment, err := deployments.Get(legacyDeploymentName(cluster), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(deployment, cluster) {
+		return nil
+	}
+
+	logger.Infof("Removing legacy master deployment %s of cluster %s", deployment.Name, cluster.Name)
+	propagation := metav1.DeletePropagationBackground
+	err = deployments.Delete(deployment.Name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
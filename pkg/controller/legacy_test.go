@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"testing"
+
+	v1beta2 "k8s.io/api/apps/v1beta2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRemoveLegacyDeployment(t *testing.T) {
+	tests := []struct {
+		name     string
+		owned    bool
+		synced   bool
+		expected bool
+	}{
+		{"owned by an unsynced cluster", true, false, true},
+		{"not owned", false, false, false},
+		{"cluster already synced", true, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cluster := newTestCluster()
+			if test.synced {
+				cluster.Status.ObservedGeneration = 1
+			}
+			deployment := &v1beta2.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      legacyDeploymentName(cluster),
+					Namespace: testNamespace,
+				},
+			}
+			if test.owned {
+				deployment.OwnerReferences = newOwnerReferences(cluster)
+			}
+			f := newFixture(t, cluster, deployment)
+			defer f.close()
+
+			if err := f.controller.removeLegacyDeployment(f.controller.Logger, cluster); err != nil {
+				t.Fatal(err)
+			}
+			_, err := f.kubeclient.AppsV1beta2().Deployments(testNamespace).Get(deployment.Name, metav1.GetOptions{})
+			if deleted := err != nil; deleted != test.expected {
+				t.Errorf("expected deployment deleted %v, got %v", test.expected, deleted)
+			}
+		})
+	}
+}
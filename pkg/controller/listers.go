@@ -0,0 +1,138 @@
+package controller
+
+import (
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	listers "github.com/matt-tyler/elasticsearch-operator/pkg/client/listers/es/v1"
+	v1beta2 "k8s.io/api/apps/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	appslisters "k8s.io/client-go/listers/apps/v1beta2"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// The listers below read from the informers of the namespace asked for.
+// Unwatched namespaces appear empty.
+
+func emptyIndexer() cache.Indexer {
+	return cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+}
+
+type clusterLister struct{ *namespaces }
+
+func (l clusterLister) List(selector labels.Selector) ([]*esV1.Cluster, error) {
+	var all []*esV1.Cluster
+	for _, informers := range l.all() {
+		items, err := informers.clusterLister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+	}
+	return all, nil
+}
+
+func (l clusterLister) Clusters(namespace string) listers.ClusterNamespaceLister {
+	if informers := l.get(namespace); informers != nil {
+		return informers.clusterLister.Clusters(namespace)
+	}
+	return listers.NewClusterLister(emptyIndexer()).Clusters(namespace)
+}
+
+type serviceLister struct{ *namespaces }
+
+func (l serviceLister) List(selector labels.Selector) ([]*corev1.Service, error) {
+	var all []*corev1.Service
+	for _, informers := range l.all() {
+		items, err := informers.serviceLister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+	}
+	return all, nil
+}
+
+func (l serviceLister) Services(namespace string) corelisters.ServiceNamespaceLister {
+	if informers := l.get(namespace); informers != nil {
+		return informers.serviceLister.Services(namespace)
+	}
+	return corelisters.NewServiceLister(emptyIndexer()).Services(namespace)
+}
+
+func (l serviceLister) GetPodServices(pod *corev1.Pod) ([]*corev1.Service, error) {
+	if informers := l.get(pod.Namespace); informers != nil {
+		return informers.serviceLister.GetPodServices(pod)
+	}
+	return nil, nil
+}
+
+type statefulSetLister struct{ *namespaces }
+
+func (l statefulSetLister) List(selector labels.Selector) ([]*v1beta2.StatefulSet, error) {
+	var all []*v1beta2.StatefulSet
+	for _, informers := range l.all() {
+		items, err := informers.statefulSetLister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+	}
+	return all, nil
+}
+
+func (l statefulSetLister) StatefulSets(namespace string) appslisters.StatefulSetNamespaceLister {
+	if informers := l.get(namespace); informers != nil {
+		return informers.statefulSetLister.StatefulSets(namespace)
+	}
+	return appslisters.NewStatefulSetLister(emptyIndexer()).StatefulSets(namespace)
+}
+
+func (l statefulSetLister) GetPodStatefulSets(pod *corev1.Pod) ([]*v1beta2.StatefulSet, error) {
+	if informers := l.get(pod.Namespace); informers != nil {
+		return informers.statefulSetLister.GetPodStatefulSets(pod)
+	}
+	return nil, nil
+}
+
+type configMapLister struct{ *namespaces }
+
+func (l configMapLister) List(selector labels.Selector) ([]*corev1.ConfigMap, error) {
+	var all []*corev1.ConfigMap
+	for _, informers := range l.all() {
+		items, err := informers.configMapLister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+	}
+	return all, nil
+}
+
+func (l configMapLister) ConfigMaps(namespace string) corelisters.ConfigMapNamespaceLister {
+	if informers := l.get(namespace); informers != nil {
+		return informers.configMapLister.ConfigMaps(namespace)
+	}
+	return corelisters.NewConfigMapLister(emptyIndexer()).ConfigMaps(namespace)
+}
+
+type podLister struct{ *namespaces }
+
+func (l podLister) List(selector labels.Selector) ([]*corev1.Pod, error) {
+	var all []*corev1.Pod
+	for _, informers := range l.all() {
+		items, err := informers.podLister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+	}
+	return all, nil
+}
+
+func (l podLister) Pods(namespace string) corelisters.PodNamespaceLister {
+	if informers := l.get(namespace); informers != nil {
+		return informers.podLister.Pods(namespace)
+	}
+	return corelisters.NewPodLister(emptyIndexer()).Pods(namespace)
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"time"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const metricsNamespace = "elasticsearch_operator"
+
+var (
+	reconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_total",
+		Help:      "Number of cluster reconciles by result, success or error",
+	}, []string{"namespace", "cluster", "result"})
+
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Time taken to reconcile a cluster",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"namespace", "cluster"})
+
+	informerSyncedDesc = prometheus.NewDesc(
+		metricsNamespace+"_informer_synced",
+		"Whether the informers of a watched namespace have synced, the namespace is empty when watching every namespace",
+		[]string{"namespace"}, nil,
+	)
+
+	clusterNodesDesiredDesc = prometheus.NewDesc(
+		metricsNamespace+"_cluster_nodes_desired",
+		"Desired number of nodes of a cluster with a role",
+		[]string{"namespace", "cluster", "role"}, nil,
+	)
+
+	clusterNodesReadyDesc = prometheus.NewDesc(
+		metricsNamespace+"_cluster_nodes_ready",
+		"Ready number of nodes of a cluster with a role",
+		[]string{"namespace", "cluster", "role"}, nil,
+	)
+
+	clusterHealthDesc = prometheus.NewDesc(
+		metricsNamespace+"_cluster_health",
+		"Health of a cluster, 2 for green, 1 for yellow, 0 for red and -1 when unknown",
+		[]string{"namespace", "cluster"}, nil,
+	)
+)
+
+func init() {
+	prometheus.MustRegister(reconcileTotal, reconcileDuration)
+	workqueue.SetProvider(workqueueMetricsProvider{})
+}
+
+// observeReconcile records the result and duration of a sync of a cluster
+func observeReconcile(namespace, name string, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	reconcileTotal.WithLabelValues(namespace, name, result).Inc()
+	reconcileDuration.WithLabelValues(namespace, name).Observe(duration.Seconds())
+}
+
+// forgetClusterMetrics removes the reconcile metrics of a deleted cluster
+func forgetClusterMetrics(namespace, name string) {
+	for _, result := range []string{"success", "error"} {
+		reconcileTotal.DeleteLabelValues(namespace, name, result)
+	}
+	reconcileDuration.DeleteLabelValues(namespace, name)
+}
+
+func healthValue(health esV1.ClusterHealth) float64 {
+	switch health {
+	case esV1.ClusterHealthGreen:
+		return 2
+	case esV1.ClusterHealthYellow:
+		return 1
+	case esV1.ClusterHealthRed:
+		return 0
+	default:
+		return -1
+	}
+}
+
+// metricsCollector reports the informers and clusters of a controller when
+// scraped, so deleted clusters disappear from the metrics
+type metricsCollector struct {
+	*Controller
+}
+
+func (m metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- informerSyncedDesc
+	ch <- clusterNodesDesiredDesc
+	ch <- clusterNodesReadyDesc
+	ch <- clusterHealthDesc
+}
+
+func (m metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, informers := range m.namespaces.all() {
+		synced := 0.0
+		if informers.hasSynced() {
+			synced = 1
+		}
+		ch <- prometheus.MustNewConstMetric(informerSyncedDesc, prometheus.GaugeValue, synced, informers.namespace)
+	}
+
+	clusters, err := m.clusterLister.List(labels.Everything())
+	if err != nil {
+		return
+	}
+	for _, cluster := range clusters {
+		for _, role := range cluster.Status.Roles {
+			ch <- prometheus.MustNewConstMetric(clusterNodesDesiredDesc, prometheus.GaugeValue, float64(role.Desired), cluster.Namespace, cluster.Name, role.Role)
+			ch <- prometheus.MustNewConstMetric(clusterNodesReadyDesc, prometheus.GaugeValue, float64(role.Ready), cluster.Namespace, cluster.Name, role.Role)
+		}
+		ch <- prometheus.MustNewConstMetric(clusterHealthDesc, prometheus.GaugeValue, healthValue(cluster.Status.Health), cluster.Namespace, cluster.Name)
+	}
+}
+
+// workqueueMetricsProvider exports the metrics of named workqueues. Latencies
+// are reported by the workqueue in microseconds.
+type workqueueMetricsProvider struct{}
+
+func (workqueueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	depth := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   metricsNamespace,
+		Subsystem:   "workqueue",
+		Name:        "depth",
+		Help:        "Current depth of the workqueue",
+		ConstLabels: prometheus.Labels{"name": name},
+	})
+	prometheus.Register(depth)
+	return depth
+}
+
+func (workqueueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	adds := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   metricsNamespace,
+		Subsystem:   "workqueue",
+		Name:        "adds_total",
+		Help:        "Number of items added to the workqueue",
+		ConstLabels: prometheus.Labels{"name": name},
+	})
+	prometheus.Register(adds)
+	return adds
+}
+
+func (workqueueMetricsProvider) NewLatencyMetric(name string) workqueue.SummaryMetric {
+	latency := prometheus.NewSummary(prometheus.SummaryOpts{
+		Namespace:   metricsNamespace,
+		Subsystem:   "workqueue",
+		Name:        "queue_latency_microseconds",
+		Help:        "Time items wait in the workqueue before being processed",
+		ConstLabels: prometheus.Labels{"name": name},
+	})
+	prometheus.Register(latency)
+	return latency
+}
+
+func (workqueueMetricsProvider) NewWorkDurationMetric(name string) workqueue.SummaryMetric {
+	workDuration := prometheus.NewSummary(prometheus.SummaryOpts{
+		Namespace:   metricsNamespace,
+		Subsystem:   "workqueue",
+		Name:        "work_duration_microseconds",
+		Help:        "Time taken to process an item of the workqueue",
+		ConstLabels: prometheus.Labels{"name": name},
+	})
+	prometheus.Register(workDuration)
+	return workDuration
+}
+
+func (workqueueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	retries := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   metricsNamespace,
+		Subsystem:   "workqueue",
+		Name:        "retries_total",
+		Help:        "Number of items requeued after failing to be processed",
+		ConstLabels: prometheus.Labels{"name": name},
+	})
+	prometheus.Register(retries)
+	return retries
+}
@@ -0,0 +1,300 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	informers "github.com/matt-tyler/elasticsearch-operator/pkg/client/informers/externalversions"
+	listers "github.com/matt-tyler/elasticsearch-operator/pkg/client/listers/es/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeinformers "k8s.io/client-go/informers"
+	appslisters "k8s.io/client-go/listers/apps/v1beta2"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Options restrict the namespaces watched by the controller. Every namespace
+// is watched when neither is set.
+type Options struct {
+	// Namespaces are always watched
+	Namespaces []string
+	// NamespaceSelector watches every namespace with matching labels, in
+	// addition to Namespaces. Namespaces are added and removed as their
+	// labels change, which requires permission to watch namespaces.
+	NamespaceSelector labels.Selector
+}
+
+func (o Options) allNamespaces() bool {
+	return len(o.Namespaces) == 0 && o.NamespaceSelector == nil
+}
+
+// namespaceInformers are the informers and listers of a single namespace, or
+// of every namespace when namespace is metav1.NamespaceAll
+type namespaceInformers struct {
+	namespace string
+
+	kubeInformerFactory kubeinformers.SharedInformerFactory
+	esInformerFactory   informers.SharedInformerFactory
+	// secretInformerFactory watches every secret, as the secrets clusters
+	// connect to elasticsearch with are created by users
+	secretInformerFactory kubeinformers.SharedInformerFactory
+
+	synced []cache.InformerSynced
+	stop   chan struct{}
+
+	clusterLister     listers.ClusterLister
+	serviceLister     corelisters.ServiceLister
+	statefulSetLister appslisters.StatefulSetLister
+	configMapLister   corelisters.ConfigMapLister
+	podLister         corelisters.PodLister
+}
+
+// newNamespaceInformers builds the informers of a namespace and registers the
+// event handlers of the controller with them. They are not started.
+func (c *Controller) newNamespaceInformers(namespace string) *namespaceInformers {
+	resyncPeriod := 0 * time.Second
+
+	listOptions := func(options *metav1.ListOptions) {
+		options.LabelSelector = labels.Set(map[string]string{
+			"operator": "elasticsearch-operator",
+		}).AsSelector().String()
+	}
+
+	kubeInformerFactory := kubeinformers.NewFilteredSharedInformerFactory(c.kubeclientset, resyncPeriod, namespace, listOptions)
+	esInformerFactory := informers.NewFilteredSharedInformerFactory(c.esclientset, resyncPeriod, namespace, nil)
+	secretInformerFactory := kubeinformers.NewFilteredSharedInformerFactory(c.kubeclientset, resyncPeriod, namespace, nil)
+
+	clusterInformer := esInformerFactory.Es().V1().Clusters()
+	serviceInformer := kubeInformerFactory.Core().V1().Services()
+	deploymentInformer := kubeInformerFactory.Apps().V1beta2().Deployments()
+	statefulSetInformer := kubeInformerFactory.Apps().V1beta2().StatefulSets()
+	configMapInformer := kubeInformerFactory.Core().V1().ConfigMaps()
+	podInformer := kubeInformerFactory.Core().V1().Pods()
+	persistentVolumeClaimInformer := kubeInformerFactory.Core().V1().PersistentVolumeClaims()
+	podDisruptionBudgetInformer := kubeInformerFactory.Policy().V1beta1().PodDisruptionBudgets()
+	secretInformer := secretInformerFactory.Core().V1().Secrets()
+
+	clusterInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if key, err := cache.MetaNamespaceKeyFunc(obj); err == nil {
+				c.queue.Add(key)
+			}
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			if key, err := cache.MetaNamespaceKeyFunc(newObj); err == nil {
+				c.queue.Add(key)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+				c.queue.Add(key)
+			}
+		},
+	})
+
+	// changes to the resources of a cluster, including manual edits, and to
+	// the readiness of its pods trigger a sync of the cluster. The legacy
+	// master deployment is watched until it has been removed.
+	ownedHandler := changedHandler(c.handleObject)
+	for _, informer := range []cache.SharedIndexInformer{
+		serviceInformer.Informer(),
+		deploymentInformer.Informer(),
+		statefulSetInformer.Informer(),
+		configMapInformer.Informer(),
+		podInformer.Informer(),
+		persistentVolumeClaimInformer.Informer(),
+		podDisruptionBudgetInformer.Informer(),
+	} {
+		informer.AddEventHandler(ownedHandler)
+	}
+	secretInformer.Informer().AddEventHandler(changedHandler(c.handleSecret))
+
+	return &namespaceInformers{
+		namespace:             namespace,
+		kubeInformerFactory:   kubeInformerFactory,
+		esInformerFactory:     esInformerFactory,
+		secretInformerFactory: secretInformerFactory,
+		synced: []cache.InformerSynced{
+			clusterInformer.Informer().HasSynced,
+			serviceInformer.Informer().HasSynced,
+			deploymentInformer.Informer().HasSynced,
+			statefulSetInformer.Informer().HasSynced,
+			configMapInformer.Informer().HasSynced,
+			podInformer.Informer().HasSynced,
+			persistentVolumeClaimInformer.Informer().HasSynced,
+			podDisruptionBudgetInformer.Informer().HasSynced,
+			secretInformer.Informer().HasSynced,
+		},
+		stop:              make(chan struct{}),
+		clusterLister:     clusterInformer.Lister(),
+		serviceLister:     serviceInformer.Lister(),
+		statefulSetLister: statefulSetInformer.Lister(),
+		configMapLister:   configMapInformer.Lister(),
+		podLister:         podInformer.Lister(),
+	}
+}
+
+// changedHandler calls handle with every object added or deleted, and with
+// every object changed by an update
+func changedHandler(handle func(obj interface{})) cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: handle,
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			oldMeta, oldOk := oldObj.(metav1.Object)
+			newMeta, newOk := newObj.(metav1.Object)
+			if oldOk && newOk && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+				return
+			}
+			handle(newObj)
+		},
+		DeleteFunc: handle,
+	}
+}
+
+func (n *namespaceInformers) start() {
+	go n.kubeInformerFactory.Start(n.stop)
+	go n.esInformerFactory.Start(n.stop)
+	go n.secretInformerFactory.Start(n.stop)
+}
+
+func (n *namespaceInformers) hasSynced() bool {
+	for _, synced := range n.synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+// namespaces holds the informers of every watched namespace
+type namespaces struct {
+	sync.RWMutex
+	informers map[string]*namespaceInformers
+}
+
+// get returns the informers watching namespace, or nil if it is not watched
+func (n *namespaces) get(namespace string) *namespaceInformers {
+	n.RLock()
+	defer n.RUnlock()
+	if informers, ok := n.informers[metav1.NamespaceAll]; ok {
+		return informers
+	}
+	return n.informers[namespace]
+}
+
+func (n *namespaces) all() []*namespaceInformers {
+	n.RLock()
+	defer n.RUnlock()
+	var all []*namespaceInformers
+	for _, informers := range n.informers {
+		all = append(all, informers)
+	}
+	return all
+}
+
+// add starts watching a namespace, returning false if it is already watched
+func (n *namespaces) add(informers *namespaceInformers) bool {
+	n.Lock()
+	defer n.Unlock()
+	if _, ok := n.informers[informers.namespace]; ok {
+		return false
+	}
+	n.informers[informers.namespace] = informers
+	informers.start()
+	return true
+}
+
+// remove stops watching a namespace
+func (n *namespaces) remove(namespace string) {
+	n.Lock()
+	defer n.Unlock()
+	if informers, ok := n.informers[namespace]; ok {
+		close(informers.stop)
+		delete(n.informers, namespace)
+	}
+}
+
+func (n *namespaces) removeAll() {
+	n.Lock()
+	defer n.Unlock()
+	for namespace, informers := range n.informers {
+		close(informers.stop)
+		delete(n.informers, namespace)
+	}
+}
+
+// hasSynced returns whether the informers of namespace have synced, which is
+// false if the namespace is not watched
+func (n *namespaces) hasSynced(namespace string) bool {
+	informers := n.get(namespace)
+	return informers != nil && informers.hasSynced()
+}
+
+func (n *namespaces) allSynced() bool {
+	for _, informers := range n.all() {
+		if !informers.hasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// watchNamespace starts watching a namespace matching the namespace selector,
+// unless it is already watched
+func (c *Controller) watchNamespace(namespace string) {
+	if c.namespaces.get(namespace) != nil {
+		return
+	}
+	if c.namespaces.add(c.newNamespaceInformers(namespace)) {
+		c.Infof("Watching namespace %s", namespace)
+	}
+}
+
+// unwatchNamespace stops watching a namespace that no longer matches the
+// namespace selector, unless it was listed explicitly
+func (c *Controller) unwatchNamespace(namespace string) {
+	if containsString(c.options.Namespaces, namespace) || c.namespaces.get(namespace) == nil {
+		return
+	}
+	c.Infof("No longer watching namespace %s", namespace)
+	c.namespaces.remove(namespace)
+}
+
+// runNamespaceSelector watches the namespaces of the cluster, watching the
+// resources of those matching the namespace selector
+func (c *Controller) runNamespaceSelector(stop <-chan struct{}) cache.InformerSynced {
+	factory := kubeinformers.NewSharedInformerFactory(c.kubeclientset, 0*time.Second)
+	informer := factory.Core().V1().Namespaces().Informer()
+
+	sync := func(obj interface{}) {
+		namespace, ok := obj.(*corev1.Namespace)
+		if !ok {
+			return
+		}
+		if c.options.NamespaceSelector.Matches(labels.Set(namespace.Labels)) {
+			c.watchNamespace(namespace.Name)
+		} else {
+			c.unwatchNamespace(namespace.Name)
+		}
+	}
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: sync,
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			sync(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if namespace, ok := obj.(*corev1.Namespace); ok {
+				c.unwatchNamespace(namespace.Name)
+			}
+		},
+	})
+
+	go factory.Start(stop)
+	return informer.HasSynced
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"testing"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	v1beta2 "k8s.io/api/apps/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHandleObjectQueuesClusterOfPod(t *testing.T) {
+	f := newFixture(t, newTestCluster())
+	defer f.close()
+	f.mustSync()
+	f.createPods("test-data", "rev", true)
+	f.refresh()
+
+	pod, err := f.kubeclient.CoreV1().Pods(testNamespace).Get("test-data-0", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller := true
+	pod.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: v1beta2.SchemeGroupVersion.String(),
+		Kind:       "StatefulSet",
+		Name:       "test-data",
+		Controller: &controller,
+	}}
+
+	f.controller.handleObject(pod)
+	if length := f.controller.queue.Len(); length != 1 {
+		t.Fatalf("expected cluster queued, got %d items", length)
+	}
+	if key, _ := f.controller.queue.Get(); key != testNamespace+"/test" {
+		t.Errorf("expected %s/test queued, got %v", testNamespace, key)
+	}
+}
+
+func TestHandleSecretQueuesConnectingCluster(t *testing.T) {
+	cluster := newTestCluster()
+	cluster.Spec.Connection = &esV1.ConnectionSpec{CredentialsSecret: "test-credentials"}
+	f := newFixture(t, cluster)
+	defer f.close()
+	f.refresh()
+
+	f.controller.handleSecret(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: testNamespace}})
+	if length := f.controller.queue.Len(); length != 0 {
+		t.Fatalf("expected nothing queued for an unrelated secret, got %d items", length)
+	}
+
+	f.controller.handleSecret(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-credentials", Namespace: testNamespace}})
+	if key, _ := f.controller.queue.Get(); key != testNamespace+"/test" {
+		t.Errorf("expected %s/test queued, got %v", testNamespace, key)
+	}
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+const elasticsearchContainerName = "elasticsearch"
+
+// applyPodTemplate strategically merges the pod template of a pool over the
+// template built by the operator, so containers, volumes and env vars are
+// merged by name. The labels selecting the pods, the ports of the
+// elasticsearch container and its discovery env are then restored, as the
+// nodes cannot be reached or find each other without them.
+func applyPodTemplate(template *v1.PodTemplateSpec, pool *esV1.NodePool, discovery []v1.EnvVar) (*v1.PodTemplateSpec, error) {
+	if pool.PodTemplate == nil {
+		return template, nil
+	}
+
+	original, err := json.Marshal(template)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := podTemplatePatch(pool.PodTemplate)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := strategicpatch.StrategicMergePatch(original, patch, v1.PodTemplateSpec{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge pod template of node pool %s: %v", pool.Name, err)
+	}
+
+	result := &v1.PodTemplateSpec{}
+	if err := json.Unmarshal(merged, result); err != nil {
+		return nil, fmt.Errorf("failed to merge pod template of node pool %s: %v", pool.Name, err)
+	}
+
+	for k, v := range template.Labels {
+		result.Labels[k] = v
+	}
+	for k, v := range template.Annotations {
+		result.Annotations[k] = v
+	}
+
+	defaults := findContainer(template.Spec.Containers, elasticsearchContainerName)
+	container := findContainer(result.Spec.Containers, elasticsearchContainerName)
+	if container == nil {
+		return nil, fmt.Errorf("pod template of node pool %s has no %s container", pool.Name, elasticsearchContainerName)
+	}
+	container.Ports = defaults.Ports
+	for _, env := range discovery {
+		container.Env = setEnv(container.Env, env)
+	}
+	return result, nil
+}
+
+// podTemplatePatch returns the pod template of a pool as a patch. Fields
+// serialized as null, such as the containers of a template that only sets
+// tolerations, are dropped as they would delete the fields of the operator.
+func podTemplatePatch(template *v1.PodTemplateSpec) ([]byte, error) {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return nil, err
+	}
+	patch := map[string]interface{}{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, err
+	}
+	return json.Marshal(dropNulls(patch))
+}
+
+func dropNulls(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for k, v := range value {
+			if v == nil {
+				delete(value, k)
+				continue
+			}
+			value[k] = dropNulls(v)
+		}
+	case []interface{}:
+		for i := range value {
+			value[i] = dropNulls(value[i])
+		}
+	}
+	return value
+}
+
+func findContainer(containers []v1.Container, name string) *v1.Container {
+	for i := range containers {
+		if containers[i].Name == name {
+			return &containers[i]
+		}
+	}
+	return nil
+}
+
+// setEnv replaces the env var of the same name, or appends it
+func setEnv(env []v1.EnvVar, value v1.EnvVar) []v1.EnvVar {
+	for i := range env {
+		if env[i].Name == value.Name {
+			env[i] = value
+			return env
+		}
+	}
+	return append(env, value)
+}
@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/matt-tyler/elasticsearch-operator/pkg/apis/es"
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	v1beta2 "k8s.io/api/apps/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// managedLabelsAnnotation and managedAnnotationsAnnotation record the keys of
+// the labels and annotations set by the controller, so those dropped from the
+// desired object are removed from the live one
+const (
+	managedLabelsAnnotation      = es.GroupName + "/managed-labels"
+	managedAnnotationsAnnotation = es.GroupName + "/managed-annotations"
+)
+
+// reconcileMetadata sets the labels and annotations of desired on updated,
+// removing those previously set by the controller that desired no longer
+// has, and returns the fields changed
+func reconcileMetadata(desired, live, updated *metav1.ObjectMeta) []string {
+	var changed []string
+
+	labels := reconcileKeys(desired.Labels, live.Labels, live.Annotations[managedLabelsAnnotation])
+	if !equality.Semantic.DeepEqual(labels, live.Labels) {
+		changed = append(changed, "metadata.labels")
+		updated.Labels = labels
+	}
+
+	annotations := reconcileKeys(desired.Annotations, live.Annotations, live.Annotations[managedAnnotationsAnnotation])
+	annotations[managedLabelsAnnotation] = managedKeys(desired.Labels)
+	annotations[managedAnnotationsAnnotation] = managedKeys(desired.Annotations)
+	if !equality.Semantic.DeepEqual(annotations, live.Annotations) {
+		changed = append(changed, "metadata.annotations")
+		updated.Annotations = annotations
+	}
+	return changed
+}
+
+// reconcileKeys returns live with the keys of desired set and the keys of
+// managed that desired no longer has removed
+func reconcileKeys(desired, live map[string]string, managed string) map[string]string {
+	result := map[string]string{}
+	for k, v := range live {
+		result[k] = v
+	}
+	for _, k := range strings.Split(managed, ",") {
+		if _, ok := desired[k]; !ok {
+			delete(result, k)
+		}
+	}
+	for k, v := range desired {
+		result[k] = v
+	}
+	return result
+}
+
+func managedKeys(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// reconcileService updates live to match the fields of desired managed by the
+// controller. Fields left unset in desired are defaulted by the api server and
+// are not treated as drift.
+func (c *Controller) reconcileService(logger log.Logger, cluster *esV1.Cluster, desired, live *corev1.Service) (*corev1.Service, error) {
+	updated := live.DeepCopy()
+	changed := reconcileMetadata(&desired.ObjectMeta, &live.ObjectMeta, &updated.ObjectMeta)
+
+	if desired.Spec.Type != live.Spec.Type {
+		changed = append(changed, "spec.type")
+		updated.Spec.Type = desired.Spec.Type
+	}
+
+	if !equality.Semantic.DeepEqual(desired.Spec.LoadBalancerSourceRanges, live.Spec.LoadBalancerSourceRanges) {
+		changed = append(changed, "spec.loadBalancerSourceRanges")
+		updated.Spec.LoadBalancerSourceRanges = desired.Spec.LoadBalancerSourceRanges
+	}
+
+	if !equality.Semantic.DeepEqual(desired.Spec.Selector, live.Spec.Selector) {
+		changed = append(changed, "spec.selector")
+		updated.Spec.Selector = desired.Spec.Selector
+	}
+
+	if !equality.Semantic.DeepDerivative(desired.Spec.Ports, live.Spec.Ports) || len(desired.Spec.Ports) != len(live.Spec.Ports) {
+		changed = append(changed, "spec.ports")
+		updated.Spec.Ports = desired.Spec.Ports
+	}
+
+	// keep the node ports allocated by the api server, which are only valid
+	// for NodePort and LoadBalancer services
+	if updated.Spec.Type == corev1.ServiceTypeClusterIP {
+		for i := range updated.Spec.Ports {
+			updated.Spec.Ports[i].NodePort = 0
+		}
+		updated.Spec.ExternalTrafficPolicy = ""
+	} else {
+		updated.Spec.Ports = preserveNodePorts(updated.Spec.Ports, live.Spec.Ports)
+	}
+
+	if len(changed) == 0 {
+		return live, nil
+	}
+
+	service, err := c.kubeclientset.CoreV1().Services(live.Namespace).Update(updated)
+	if err != nil {
+		return nil, err
+	}
+	c.recordUpdate(logger, cluster, service.Name, changed)
+	return service, nil
+}
+
+// preserveNodePorts copies the node ports of live to the matching ports of
+// desired that do not request one
+func preserveNodePorts(desired, live []corev1.ServicePort) []corev1.ServicePort {
+	ports := make([]corev1.ServicePort, len(desired))
+	copy(ports, desired)
+	for i := range ports {
+		if ports[i].NodePort != 0 {
+			continue
+		}
+		for _, port := range live {
+			if port.Name == ports[i].Name {
+				ports[i].NodePort = port.NodePort
+			}
+		}
+	}
+	return ports
+}
+
+// reconcileStatefulSet updates live to match the fields of desired managed by
+// the controller. Immutable fields such as the selector and volume claim
+// templates are left untouched, and the template is replaced whenever it was
+// built differently, so removed containers, volumes or env vars are removed.
+func (c *Controller) reconcileStatefulSet(logger log.Logger, cluster *esV1.Cluster, desired, live *v1beta2.StatefulSet) (*v1beta2.StatefulSet, error) {
+	updated := live.DeepCopy()
+	changed := reconcileMetadata(&desired.ObjectMeta, &live.ObjectMeta, &updated.ObjectMeta)
+
+	if !equality.Semantic.DeepEqual(desired.Spec.Replicas, live.Spec.Replicas) {
+		changed = append(changed, "spec.replicas")
+		updated.Spec.Replicas = desired.Spec.Replicas
+	}
+
+	if !equality.Semantic.DeepDerivative(desired.Spec.UpdateStrategy, live.Spec.UpdateStrategy) {
+		changed = append(changed, "spec.updateStrategy")
+		updated.Spec.UpdateStrategy = desired.Spec.UpdateStrategy
+	}
+
+	// the live template is defaulted by the api server, so it is compared by
+	// the hash of the template it was built from
+	if desired.Annotations[templateHashAnnotation] != live.Annotations[templateHashAnnotation] {
+		changed = append(changed, "spec.template")
+		updated.Spec.Template = desired.Spec.Template
+	}
+
+	if len(changed) == 0 {
+		return live, nil
+	}
+
+	statefulSet, err := c.kubeclientset.AppsV1beta2().StatefulSets(live.Namespace).Update(updated)
+	if err != nil {
+		return nil, err
+	}
+	c.recordUpdate(logger, cluster, statefulSet.Name, changed)
+	return statefulSet, nil
+}
+
+func (c *Controller) recordUpdate(logger log.Logger, cluster *esV1.Cluster, name string, changed []string) {
+	msg := fmt.Sprintf(MessageResourceUpdated, name, strings.Join(changed, ", "))
+	logger.Infof("%s", msg)
+	c.recorder.Event(cluster, corev1.EventTypeNormal, SuccessUpdated, msg)
+}
@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"testing"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/validation"
+	v1beta2 "k8s.io/api/apps/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func newReconcileController(objects ...runtime.Object) *Controller {
+	return &Controller{
+		Logger:        log.NewLogger(),
+		kubeclientset: kubefake.NewSimpleClientset(objects...),
+		recorder:      record.NewFakeRecorder(100),
+	}
+}
+
+func newReconcileCluster() *esV1.Cluster {
+	cluster := &esV1.Cluster{}
+	cluster.Name = "test"
+	cluster.Namespace = "default"
+	cluster.Labels = map[string]string{"team": "search"}
+	cluster.Spec.Version = "6.4.2"
+	return cluster
+}
+
+func newReconcilePool(template *corev1.PodTemplateSpec) *esV1.NodePool {
+	replicas := int32(1)
+	return &esV1.NodePool{
+		Name:        "data",
+		Roles:       []esV1.NodeRole{esV1.NodeRoleData},
+		Replicas:    &replicas,
+		PodTemplate: template,
+	}
+}
+
+func poolStatefulSet(t *testing.T, cluster *esV1.Cluster, pool *esV1.NodePool) *v1beta2.StatefulSet {
+	configMap, err := newPoolConfigMap(cluster, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statefulSet, err := newNodeStatefulSet(cluster, pool, version{validation.Version{Major: 6, Minor: 4, Patch: 2}}, "test-master-service", 1, configMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statefulSet
+}
+
+func TestReconcileStatefulSetRemovesTemplateFields(t *testing.T) {
+	full := &corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: elasticsearchContainerName,
+				Env:  []corev1.EnvVar{{Name: "EXTRA", Value: "1"}},
+			}, {
+				Name:  "sidecar",
+				Image: "busybox",
+			}},
+			Tolerations: []corev1.Toleration{{Key: "dedicated", Value: "search", Effect: corev1.TaintEffectNoSchedule}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		template func() *corev1.PodTemplateSpec
+		removed  func(*corev1.PodTemplateSpec) bool
+	}{
+		{"container", func() *corev1.PodTemplateSpec {
+			template := full.DeepCopy()
+			template.Spec.Containers = template.Spec.Containers[:1]
+			return template
+		}, func(template *corev1.PodTemplateSpec) bool {
+			return findContainer(template.Spec.Containers, "sidecar") == nil
+		}},
+		{"env var", func() *corev1.PodTemplateSpec {
+			template := full.DeepCopy()
+			template.Spec.Containers[0].Env = nil
+			return template
+		}, func(template *corev1.PodTemplateSpec) bool {
+			for _, env := range findContainer(template.Spec.Containers, elasticsearchContainerName).Env {
+				if env.Name == "EXTRA" {
+					return false
+				}
+			}
+			return true
+		}},
+		{"toleration", func() *corev1.PodTemplateSpec {
+			template := full.DeepCopy()
+			template.Spec.Tolerations = nil
+			return template
+		}, func(template *corev1.PodTemplateSpec) bool {
+			return len(template.Spec.Tolerations) == 0
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cluster := newReconcileCluster()
+			live := poolStatefulSet(t, cluster, newReconcilePool(full))
+			live.Namespace = cluster.Namespace
+			c := newReconcileController(live)
+
+			desired := poolStatefulSet(t, cluster, newReconcilePool(test.template()))
+			updated, err := c.reconcileStatefulSet(c.Logger, cluster, desired, live)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !test.removed(&updated.Spec.Template) {
+				t.Errorf("expected %s removed from the template", test.name)
+			}
+			if hash := updated.Annotations[templateHashAnnotation]; hash != desired.Annotations[templateHashAnnotation] {
+				t.Errorf("expected template hash %s, got %s", desired.Annotations[templateHashAnnotation], hash)
+			}
+		})
+	}
+}
+
+func TestReconcileStatefulSetUnchanged(t *testing.T) {
+	cluster := newReconcileCluster()
+	desired := poolStatefulSet(t, cluster, newReconcilePool(nil))
+	live := desired.DeepCopy()
+	live.Namespace = cluster.Namespace
+	live.Annotations[managedLabelsAnnotation] = managedKeys(desired.Labels)
+	live.Annotations[managedAnnotationsAnnotation] = managedKeys(desired.Annotations)
+	// fields defaulted by the api server
+	live.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyAlways
+	live.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirst
+
+	c := newReconcileController(live)
+	updated, err := c.reconcileStatefulSet(c.Logger, cluster, desired, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != live {
+		t.Errorf("expected no update of an unchanged statefulset")
+	}
+}
+
+func TestReconcileServiceRemovesMetadata(t *testing.T) {
+	cluster := newReconcileCluster()
+	cluster.Spec.HTTP = &esV1.HTTPSpec{
+		Annotations: map[string]string{"managed": "true", "removed": "true"},
+	}
+	live := newHTTPService(cluster)
+	live.Namespace = cluster.Namespace
+	c := newReconcileController(live)
+
+	live, err := c.reconcileService(c.Logger, cluster, newHTTPService(cluster), live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// set by another controller, so kept
+	live.Annotations["external"] = "true"
+
+	delete(cluster.Spec.HTTP.Annotations, "removed")
+	delete(cluster.Labels, "team")
+	updated, err := c.reconcileService(c.Logger, cluster, newHTTPService(cluster), live)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := updated.Annotations["removed"]; ok {
+		t.Errorf("expected annotation removed from the spec removed, got %v", updated.Annotations)
+	}
+	if updated.Annotations["managed"] != "true" || updated.Annotations["external"] != "true" {
+		t.Errorf("expected other annotations kept, got %v", updated.Annotations)
+	}
+	if _, ok := updated.Labels["team"]; ok {
+		t.Errorf("expected label removed from the cluster removed, got %v", updated.Labels)
+	}
+}
@@ -1,115 +1,382 @@
 package controller
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
+	"github.com/ghodss/yaml"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/apis/es"
 	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/validation"
 	v1beta2 "k8s.io/api/apps/v1beta2"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-func newMasterDeployment(cluster *esV1.Cluster, serviceURL string) *v1beta2.Deployment {
-	replicas := int32(1)
-	minimumNodes := string((replicas + 1) / 2)
-	selector := metav1.LabelSelector{
+const (
+	elasticsearchImage = "docker.elastic.co/elasticsearch/elasticsearch-oss"
+	dataVolumeName     = "data"
+	dataMountPath      = "/usr/share/elasticsearch/data"
+	configVolumeName   = "config"
+	configMountPath    = "/usr/share/elasticsearch/config/elasticsearch.yml"
+	configKey          = "elasticsearch.yml"
+
+	// configHashAnnotation records the hash of the elasticsearch.yml of a
+	// pod template, so a config change rolls the nodes of the pool
+	configHashAnnotation = es.GroupName + "/config-hash"
+	// templateHashAnnotation records the hash of the pod template a
+	// statefulset was built with, as the api server defaults the fields of
+	// the live template
+	templateHashAnnotation = es.GroupName + "/template-hash"
+
+	clusterLabel = "cluster"
+	poolLabel    = "pool"
+	// httpLabel marks the pods receiving client traffic from the http service
+	httpLabel = "http"
+
+	initialMasterNodesKey = "cluster.initial_master_nodes"
+)
+
+var defaultStorageSize = resource.MustParse(esV1.DefaultStorageSize)
+
+func masterServiceName(cluster *esV1.Cluster) string {
+	return fmt.Sprintf("%v-master-service", cluster.Name)
+}
+
+func httpServiceName(cluster *esV1.Cluster) string {
+	return fmt.Sprintf("%v-http", cluster.Name)
+}
+
+// poolServiceName is the governing service of the statefulset of a pool and
+// shares its name
+func poolServiceName(cluster *esV1.Cluster, pool *esV1.NodePool) string {
+	return poolStatefulSetName(cluster, pool)
+}
+
+func poolStatefulSetName(cluster *esV1.Cluster, pool *esV1.NodePool) string {
+	return fmt.Sprintf("%v-%v", cluster.Name, pool.Name)
+}
+
+func bootstrapConfigMapName(cluster *esV1.Cluster) string {
+	return fmt.Sprintf("%v-bootstrap", cluster.Name)
+}
+
+func poolConfigMapName(cluster *esV1.Cluster, pool *esV1.NodePool) string {
+	return fmt.Sprintf("%v-config", poolStatefulSetName(cluster, pool))
+}
+
+// nodeNames returns the names of the nodes of a pool with ordinals in
+// [from, to). Nodes are named after their pods.
+func nodeNames(cluster *esV1.Cluster, pool *esV1.NodePool, from, to int32) []string {
+	var names []string
+	for i := from; i < to; i++ {
+		names = append(names, fmt.Sprintf("%v-%d", poolStatefulSetName(cluster, pool), i))
+	}
+	return names
+}
+
+// initialMasterNodes returns the names of every master eligible node
+func initialMasterNodes(cluster *esV1.Cluster, pools []esV1.NodePool) []string {
+	var names []string
+	for i := range pools {
+		if pools[i].HasRole(esV1.NodeRoleMaster) {
+			names = append(names, nodeNames(cluster, &pools[i], 0, *pools[i].Replicas)...)
+		}
+	}
+	return names
+}
+
+// image returns the elasticsearch image of the cluster, the official image of
+// version v unless overridden by the spec
+func image(cluster *esV1.Cluster, v version) string {
+	if cluster.Spec.Image != "" {
+		return cluster.Spec.Image
+	}
+	return fmt.Sprintf("%v:%v", elasticsearchImage, v)
+}
+
+// roleLabel is the pod label recording whether a node has role
+func roleLabel(role esV1.NodeRole) string {
+	return "node." + string(role)
+}
+
+// poolSelector selects the pods of a single node pool
+func poolSelector(cluster *esV1.Cluster, pool *esV1.NodePool) metav1.LabelSelector {
+	return metav1.LabelSelector{
 		MatchLabels: map[string]string{
-			"role": "master",
+			clusterLabel: cluster.Name,
+			poolLabel:    pool.Name,
 		},
 	}
+}
+
+// dataSelector selects the data nodes of a cluster, the pods scaled by its
+// scale subresource
+func dataSelector(cluster *esV1.Cluster) labels.Selector {
+	return labels.SelectorFromSet(labels.Set{
+		clusterLabel:                 cluster.Name,
+		roleLabel(esV1.NodeRoleData): "true",
+	})
+}
+
+// podLabels returns the labels of the pods of a node pool, which record the
+// roles of the nodes alongside the labels of the cluster
+func podLabels(cluster *esV1.Cluster, pool *esV1.NodePool) map[string]string {
+	labels := map[string]string{}
+	for k, v := range cluster.Labels {
+		labels[k] = v
+	}
+	for k, v := range poolSelector(cluster, pool).MatchLabels {
+		labels[k] = v
+	}
+	labels["operator"] = "elasticsearch-operator"
+	for _, role := range []esV1.NodeRole{esV1.NodeRoleMaster, esV1.NodeRoleData, esV1.NodeRoleIngest, esV1.NodeRoleML} {
+		labels[roleLabel(role)] = strconv.FormatBool(pool.HasRole(role))
+	}
+	labels[httpLabel] = strconv.FormatBool(servesHTTP(pool))
+	return labels
+}
+
+// servesHTTP returns whether client requests are sent to the nodes of a pool,
+// which is true of data and coordinating-only nodes
+func servesHTTP(pool *esV1.NodePool) bool {
+	return pool.HasRole(esV1.NodeRoleData) || pool.IsCoordinatingOnly()
+}
 
+// resourceLabels returns the labels applied to every resource owned by the cluster
+func resourceLabels(cluster *esV1.Cluster) map[string]string {
 	labels := map[string]string{}
 	for k, v := range cluster.Labels {
-		metav1.AddLabelToSelector(&selector, k, v)
 		labels[k] = v
 	}
 	labels["operator"] = "elasticsearch-operator"
+	return labels
+}
+
+func newOwnerReferences(cluster *esV1.Cluster) []metav1.OwnerReference {
+	return []metav1.OwnerReference{
+		*metav1.NewControllerRef(cluster, schema.GroupVersionKind{
+			Group:   esV1.SchemeGroupVersion.Group,
+			Version: esV1.SchemeGroupVersion.Version,
+			Kind:    "Cluster",
+		}),
+	}
+}
+
+// nodeConfig returns the elasticsearch.yml settings of the nodes of a pool.
+// Pool settings take precedence over cluster settings, and the settings of
+// the operator over both. node.ml is only set when requested as the setting
+// is unknown to the oss distribution.
+func nodeConfig(cluster *esV1.Cluster, pool *esV1.NodePool) map[string]string {
+	config := map[string]string{
+		// nodes unable to lock memory can disable it through spec.config
+		"bootstrap.memory_lock": "true",
+	}
+	for k, v := range cluster.Spec.Config {
+		config[k] = v
+	}
+	for k, v := range pool.Config {
+		config[k] = v
+	}
+
+	config["cluster.name"] = cluster.Name
+	config["network.host"] = "${HOSTNAME}"
+	// nodes are matched to their pods by name, which only defaults to the
+	// hostname from 7.0
+	config["node.name"] = "${HOSTNAME}"
+	config["node.master"] = strconv.FormatBool(pool.HasRole(esV1.NodeRoleMaster))
+	config["node.data"] = strconv.FormatBool(pool.HasRole(esV1.NodeRoleData))
+	config["node.ingest"] = strconv.FormatBool(pool.HasRole(esV1.NodeRoleIngest))
+	if pool.HasRole(esV1.NodeRoleML) {
+		config["node.ml"] = "true"
+	}
+	return config
+}
+
+// configHash returns the hash of an elasticsearch.yml
+func configHash(config string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(config)))
+}
+
+// templateHash returns the hash of a pod template
+func templateHash(template *v1.PodTemplateSpec) (string, error) {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
 
-	deployment := &v1beta2.Deployment{
+// heapSize returns the JVM heap of the nodes of a pool, half their memory
+// limit unless overridden. Nodes without either use the default heap of the
+// image.
+func heapSize(pool *esV1.NodePool) *resource.Quantity {
+	if pool.Heap != nil {
+		return pool.Heap
+	}
+	return validation.DefaultHeap(pool.Resources)
+}
+
+// javaOptsEnv sets the minimum and maximum heap of the nodes of a pool to
+// the same size, so the heap is never resized
+func javaOptsEnv(pool *esV1.NodePool) []v1.EnvVar {
+	heap := heapSize(pool)
+	if heap == nil {
+		return nil
+	}
+	megabytes := heap.Value() >> 20
+	if megabytes < 1 {
+		megabytes = 1
+	}
+	return []v1.EnvVar{
+		{Name: "ES_JAVA_OPTS", Value: fmt.Sprintf("-Xms%dm -Xmx%dm", megabytes, megabytes)},
+	}
+}
+
+// discoveryEnv returns the settings nodes use to find the master nodes. Zen2
+// clusters read cluster.initial_master_nodes from the bootstrap config map,
+// which is removed once the cluster has formed without restarting any nodes.
+func discoveryEnv(cluster *esV1.Cluster, v version, masterServiceURL string, masterNodes int32) []v1.EnvVar {
+	if v.usesZen2() {
+		optional := true
+		return []v1.EnvVar{
+			{Name: "discovery.seed_hosts", Value: masterServiceURL},
+			{Name: initialMasterNodesKey, ValueFrom: &v1.EnvVarSource{
+				ConfigMapKeyRef: &v1.ConfigMapKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{Name: bootstrapConfigMapName(cluster)},
+					Key:                  initialMasterNodesKey,
+					Optional:             &optional,
+				},
+			}},
+		}
+	}
+
+	minimumNodes := strconv.Itoa(int(masterNodes/2 + 1))
+	return []v1.EnvVar{
+		{Name: "discovery.zen.ping.unicast.hosts", Value: masterServiceURL},
+		{Name: "discovery.zen.minimum_master_nodes", Value: minimumNodes},
+	}
+}
+
+// return a config map holding the master nodes used to bootstrap a zen2 cluster
+func newBootstrapConfigMap(cluster *esV1.Cluster, masterNodes []string) *v1.ConfigMap {
+	return &v1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   fmt.Sprintf("%v-master-deployment", cluster.Name),
-			Labels: labels,
-			OwnerReferences: []metav1.OwnerReference{
-				*metav1.NewControllerRef(cluster, schema.GroupVersionKind{
-					Group:   esV1.SchemeGroupVersion.Group,
-					Version: esV1.SchemeGroupVersion.Version,
-					Kind:    "Cluster",
-				}),
-			},
+			Name:            bootstrapConfigMapName(cluster),
+			Labels:          resourceLabels(cluster),
+			OwnerReferences: newOwnerReferences(cluster),
 		},
-		Spec: v1beta2.DeploymentSpec{
-			Strategy: v1beta2.DeploymentStrategy{
-				Type: v1beta2.RollingUpdateDeploymentStrategyType,
-				RollingUpdate: &v1beta2.RollingUpdateDeployment{
-					MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
-					MaxSurge:       &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
-				},
-			},
-			Replicas: &replicas,
-			Selector: &selector,
-			Template: v1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: selector.MatchLabels,
-				},
-				Spec: v1.PodSpec{
-					Containers: []v1.Container{{
-						Name:            "elastic-master",
-						Image:           "docker.elastic.co/elasticsearch/elasticsearch-oss:6.1.1",
-						ImagePullPolicy: v1.PullIfNotPresent,
-						Ports: []v1.ContainerPort{{
-							ContainerPort: 9200,
-						}, {
-							ContainerPort: 9300,
-						}},
-						Env: []v1.EnvVar{
-							v1.EnvVar{"cluster.name", cluster.Name, nil},
-							v1.EnvVar{"network.host", "$${HOSTNAME}", nil},
-							v1.EnvVar{"boostrap.memory_lock", "true", nil},
-							v1.EnvVar{"node.master", "true", nil},
-							v1.EnvVar{"node.data", "false", nil},
-							v1.EnvVar{"discovery.zen.ping.unicast.hosts", serviceURL, nil},
-							v1.EnvVar{"discovery.zen.minimum_master_nodes", minimumNodes, nil},
-						},
-					}},
-				},
-			},
+		Data: map[string]string{
+			initialMasterNodesKey: strings.Join(masterNodes, ","),
 		},
 	}
-	return deployment
+}
+
+// return a config map holding the elasticsearch.yml of the nodes of a pool
+func newPoolConfigMap(cluster *esV1.Cluster, pool *esV1.NodePool) (*v1.ConfigMap, error) {
+	config, err := yaml.Marshal(nodeConfig(cluster, pool))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render config of node pool %s: %v", pool.Name, err)
+	}
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            poolConfigMapName(cluster, pool),
+			Labels:          resourceLabels(cluster),
+			OwnerReferences: newOwnerReferences(cluster),
+		},
+		Data: map[string]string{
+			configKey: string(config),
+		},
+	}, nil
 }
 
 // return a headless service for master discovery
 func newMasterService(cluster *esV1.Cluster) *v1.Service {
-	selector := metav1.LabelSelector{
-		MatchLabels: map[string]string{
-			"role": "master",
+	return newHeadlessService(cluster, masterServiceName(cluster), map[string]string{
+		clusterLabel:                   cluster.Name,
+		roleLabel(esV1.NodeRoleMaster): "true",
+	})
+}
+
+// return a load balanced service for clients of the cluster, configured by
+// spec.http
+func newHTTPService(cluster *esV1.Cluster) *v1.Service {
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            httpServiceName(cluster),
+			Labels:          resourceLabels(cluster),
+			OwnerReferences: newOwnerReferences(cluster),
+		},
+		Spec: v1.ServiceSpec{
+			Type: v1.ServiceTypeClusterIP,
+			Selector: map[string]string{
+				clusterLabel: cluster.Name,
+				httpLabel:    "true",
+			},
+			Ports: []v1.ServicePort{{
+				Name: "rest",
+				Port: 9200,
+			}},
 		},
 	}
-	labels := map[string]string{}
-	for k, v := range cluster.Labels {
-		metav1.AddLabelToSelector(&selector, k, v)
-		labels[k] = v
+
+	if http := cluster.Spec.HTTP; http != nil {
+		if http.ServiceType != "" {
+			service.Spec.Type = http.ServiceType
+		}
+		service.Annotations = http.Annotations
+		if service.Spec.Type == v1.ServiceTypeLoadBalancer {
+			service.Spec.LoadBalancerSourceRanges = http.LoadBalancerSourceRanges
+		}
 	}
-	labels["operator"] = "elasticsearch-operator"
+	return service
+}
 
+// httpEndpoint returns the url of the http service, using the address of its
+// load balancer once one has been provisioned
+func httpEndpoint(cluster *esV1.Cluster, service *v1.Service) string {
+	scheme := "http"
+	if cluster.Spec.Connection != nil && cluster.Spec.Connection.Scheme != "" {
+		scheme = cluster.Spec.Connection.Scheme
+	}
+
+	if service.Spec.Type == v1.ServiceTypeLoadBalancer {
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			host := ingress.Hostname
+			if host == "" {
+				host = ingress.IP
+			}
+			if host != "" {
+				return fmt.Sprintf("%v://%v:9200", scheme, host)
+			}
+		}
+	}
+	return fmt.Sprintf("%v://%v.%v.svc:9200", scheme, service.Name, service.Namespace)
+}
+
+// return a headless service giving the nodes of a pool stable network identities
+func newPoolService(cluster *esV1.Cluster, pool *esV1.NodePool) *v1.Service {
+	return newHeadlessService(cluster, poolServiceName(cluster, pool), poolSelector(cluster, pool).MatchLabels)
+}
+
+func newHeadlessService(cluster *esV1.Cluster, name string, selector map[string]string) *v1.Service {
 	service := &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   fmt.Sprintf("%v-master-service", cluster.Name),
-			Labels: labels,
-			OwnerReferences: []metav1.OwnerReference{
-				*metav1.NewControllerRef(cluster, schema.GroupVersionKind{
-					Group:   esV1.SchemeGroupVersion.Group,
-					Version: esV1.SchemeGroupVersion.Version,
-					Kind:    "Cluster",
-				}),
-			},
+			Name:            name,
+			Labels:          resourceLabels(cluster),
+			OwnerReferences: newOwnerReferences(cluster),
 		},
 		Spec: v1.ServiceSpec{
 			Type:      "ClusterIP",
 			ClusterIP: "None",
-			Selector:  selector.MatchLabels,
+			Selector:  selector,
 			Ports: []v1.ServicePort{{
 				Name: "rest",
 				Port: 9200,
@@ -122,7 +389,132 @@ func newMasterService(cluster *esV1.Cluster) *v1.Service {
 	return service
 }
 
-// Default Pod Template
-// take template and create master and data nodes
+// return a statefulset running the nodes of a pool with the elasticsearch.yml
+// of config map, merged with the pod template of the pool. Nodes with storage
+// get their own persistent volume claim, coordinating-only nodes use an
+// emptyDir.
+func newNodeStatefulSet(cluster *esV1.Cluster, pool *esV1.NodePool, v version, masterServiceURL string, masterNodes int32, configMap *v1.ConfigMap) (*v1beta2.StatefulSet, error) {
+	selector := poolSelector(cluster, pool)
+
+	discovery := discoveryEnv(cluster, v, masterServiceURL, masterNodes)
+	env := append([]v1.EnvVar{}, discovery...)
+	env = append(env, javaOptsEnv(pool)...)
+
+	statefulSet := &v1beta2.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            poolStatefulSetName(cluster, pool),
+			Labels:          resourceLabels(cluster),
+			OwnerReferences: newOwnerReferences(cluster),
+		},
+		Spec: v1beta2.StatefulSetSpec{
+			ServiceName:         poolServiceName(cluster, pool),
+			PodManagementPolicy: v1beta2.ParallelPodManagement,
+			// pods are restarted one at a time by the controller, see syncUpgrade
+			UpdateStrategy: v1beta2.StatefulSetUpdateStrategy{
+				Type: v1beta2.OnDeleteStatefulSetStrategyType,
+			},
+			Replicas: pool.Replicas,
+			Selector: &selector,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: podLabels(cluster, pool),
+					Annotations: map[string]string{
+						configHashAnnotation: configHash(configMap.Data[configKey]),
+					},
+				},
+				Spec: v1.PodSpec{
+					ImagePullSecrets: cluster.Spec.ImagePullSecrets,
+					Containers: []v1.Container{{
+						Name:            elasticsearchContainerName,
+						Image:           image(cluster, v),
+						ImagePullPolicy: v1.PullIfNotPresent,
+						Ports: []v1.ContainerPort{{
+							ContainerPort: 9200,
+						}, {
+							ContainerPort: 9300,
+						}},
+						Env:       env,
+						Resources: pool.Resources,
+						ReadinessProbe: &v1.Probe{
+							Handler: v1.Handler{
+								TCPSocket: &v1.TCPSocketAction{
+									Port: intstr.FromInt(9200),
+								},
+							},
+						},
+						VolumeMounts: []v1.VolumeMount{{
+							Name:      dataVolumeName,
+							MountPath: dataMountPath,
+						}, {
+							Name:      configVolumeName,
+							MountPath: configMountPath,
+							SubPath:   configKey,
+						}},
+					}},
+					Volumes: []v1.Volume{{
+						Name: configVolumeName,
+						VolumeSource: v1.VolumeSource{
+							ConfigMap: &v1.ConfigMapVolumeSource{
+								LocalObjectReference: v1.LocalObjectReference{Name: configMap.Name},
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+
+	if pool.Storage == nil {
+		statefulSet.Spec.Template.Spec.Volumes = append(statefulSet.Spec.Template.Spec.Volumes, v1.Volume{
+			Name: dataVolumeName,
+			VolumeSource: v1.VolumeSource{
+				EmptyDir: &v1.EmptyDirVolumeSource{},
+			},
+		})
+	} else {
+		statefulSet.Spec.VolumeClaimTemplates = newVolumeClaimTemplates(cluster, pool)
+	}
+
+	template, err := applyPodTemplate(&statefulSet.Spec.Template, pool, discovery)
+	if err != nil {
+		return nil, err
+	}
+	statefulSet.Spec.Template = *template
+
+	hash, err := templateHash(template)
+	if err != nil {
+		return nil, err
+	}
+	statefulSet.Annotations = map[string]string{templateHashAnnotation: hash}
+	return statefulSet, nil
+}
+
+// newVolumeClaimTemplates returns the persistent volume claim of each node of
+// a pool with storage
+func newVolumeClaimTemplates(cluster *esV1.Cluster, pool *esV1.NodePool) []v1.PersistentVolumeClaim {
+	storageSize := pool.Storage.Size
+	if storageSize.IsZero() {
+		storageSize = defaultStorageSize
+	}
 
-// statefulset for data nodes
+	accessModes := pool.Storage.AccessModes
+	if len(accessModes) == 0 {
+		accessModes = []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce}
+	}
+
+	return []v1.PersistentVolumeClaim{{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   dataVolumeName,
+			Labels: resourceLabels(cluster),
+		},
+		Spec: v1.PersistentVolumeClaimSpec{
+			StorageClassName: pool.Storage.StorageClassName,
+			AccessModes:      accessModes,
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{
+					v1.ResourceStorage: storageSize,
+				},
+			},
+		},
+	}}
+}
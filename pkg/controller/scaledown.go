@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// ErrScaleDownBlocked is used as part of the Event 'reason' when data nodes
+	// cannot be removed without losing replicas
+	ErrScaleDownBlocked = "ErrScaleDownBlocked"
+
+	// MessageScaleDownBlocked is the message used for events when data nodes
+	// cannot be removed without losing replicas
+	MessageScaleDownBlocked = "Cannot remove nodes %v: %d remaining data nodes cannot hold indices with %d replicas"
+)
+
+// syncScaleDown drains the data nodes that are about to be removed by scaling
+// down a pool. Shard allocation is excluded from the leaving nodes and the
+// pools are held at their current size until those nodes hold no shards. The
+// returned pools have their replicas adjusted to the size they may be
+// scaled to now.
+func (c *Controller) syncScaleDown(logger log.Logger, cluster *esV1.Cluster, pools []esV1.NodePool, observed *observedState) ([]esV1.NodePool, error) {
+	var leaving []string
+	leavingByPool := map[int][]string{}
+	current := map[int]int32{}
+	var remaining int32
+
+	for i := range pools {
+		pool := &pools[i]
+		if !pool.HasRole(esV1.NodeRoleData) {
+			continue
+		}
+		remaining += *pool.Replicas
+
+		statefulSet, err := c.statefulSetLister.StatefulSets(cluster.Namespace).Get(poolStatefulSetName(cluster, pool))
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if statefulSet.Spec.Replicas == nil || *statefulSet.Spec.Replicas <= *pool.Replicas {
+			continue
+		}
+
+		names := nodeNames(cluster, pool, *pool.Replicas, *statefulSet.Spec.Replicas)
+		leaving = append(leaving, names...)
+		leavingByPool[i] = names
+		current[i] = *statefulSet.Spec.Replicas
+	}
+
+	observed.leavingNodes = leaving
+	if len(leaving) == 0 {
+		return pools, nil
+	}
+
+	held := make([]esV1.NodePool, len(pools))
+	copy(held, pools)
+	hold := func(i int) {
+		replicas := current[i]
+		held[i].Replicas = &replicas
+	}
+	holdAll := func() []esV1.NodePool {
+		for i := range current {
+			hold(i)
+		}
+		observed.requeue = true
+		return held
+	}
+
+	client, err := c.esClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	indices, err := client.Indices()
+	if err != nil {
+		logger.Infof("Cannot check the replicas of cluster %s before scaling down: %v", cluster.Name, err)
+		return holdAll(), nil
+	}
+
+	maxReplicas := 0
+	for _, index := range indices {
+		if replicas, err := strconv.Atoi(index.Replicas); err == nil && replicas > maxReplicas {
+			maxReplicas = replicas
+		}
+	}
+
+	if remaining < int32(maxReplicas+1) {
+		msg := fmt.Sprintf(MessageScaleDownBlocked, leaving, remaining, maxReplicas)
+		c.recorder.Event(cluster, corev1.EventTypeWarning, ErrScaleDownBlocked, msg)
+		observed.scaleDownBlocked = msg
+		return holdAll(), nil
+	}
+
+	if !equalStrings(observed.excludedNodes, leaving) {
+		logger.Infof("Moving shards of cluster %s off nodes %v", cluster.Name, leaving)
+		if err := client.ExcludeNodes(leaving); err != nil {
+			return nil, err
+		}
+		observed.excludedNodes = leaving
+	}
+
+	shards, err := client.Shards()
+	if err != nil {
+		logger.Infof("Cannot check the shards of cluster %s before scaling down: %v", cluster.Name, err)
+		return holdAll(), nil
+	}
+
+	shardsOnNode := map[string]int{}
+	for _, shard := range shards {
+		shardsOnNode[shard.Node]++
+	}
+
+	for i, names := range leavingByPool {
+		for _, name := range names {
+			if shardsOnNode[name] > 0 {
+				logger.Infof("Waiting for %d shards to move off node %s", shardsOnNode[name], name)
+				hold(i)
+				observed.requeue = true
+				break
+			}
+		}
+	}
+	return held, nil
+}
+
+// finishScaleDown clears the allocation exclusion once the drained nodes
+// have been removed
+func (c *Controller) finishScaleDown(logger log.Logger, cluster *esV1.Cluster, observed *observedState) error {
+	if len(observed.excludedNodes) == 0 || len(observed.leavingNodes) > 0 {
+		return nil
+	}
+	if observed.dataScalingDown {
+		observed.requeue = true
+		return nil
+	}
+
+	logger.Infof("Clearing allocation exclusions of cluster %s", cluster.Name)
+	client, err := c.esClient(cluster)
+	if err != nil {
+		return err
+	}
+	if err := client.ExcludeNodes(nil); err != nil {
+		return err
+	}
+	observed.excludedNodes = nil
+	return nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
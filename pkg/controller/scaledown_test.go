@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/esclient"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const excludeSetting = "cluster.routing.allocation.exclude._name"
+
+func TestScaleDown(t *testing.T) {
+	tests := []struct {
+		name    string
+		shards  []esclient.Shard
+		indices []esclient.Index
+
+		expectedReplicas int32
+		expectedExcluded interface{}
+		expectedBlocked  bool
+	}{
+		{"shards remain on the leaving node",
+			[]esclient.Shard{{Index: "logs", Shard: "0", Node: "test-data-2"}},
+			[]esclient.Index{{Index: "logs", Replicas: "1"}},
+			3, "test-data-2", false},
+		{"shard relocating off the leaving node",
+			[]esclient.Shard{{Index: "logs", Shard: "0", State: "RELOCATING", Node: "test-data-2 -> 10.0.0.1 Xo2Lw6fCQAqQ4a0bTsAq3Q test-data-0"}},
+			[]esclient.Index{{Index: "logs", Replicas: "1"}},
+			3, "test-data-2", false},
+		{"leaving node drained",
+			[]esclient.Shard{{Index: "logs", Shard: "0", Node: "test-data-0"}},
+			[]esclient.Index{{Index: "logs", Replicas: "1"}},
+			2, "test-data-2", false},
+		{"too many replicas for the remaining nodes",
+			nil,
+			[]esclient.Index{{Index: "logs", Replicas: "2"}},
+			3, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cluster := newTestCluster()
+			cluster.Spec.Size = 3
+			f := newFixture(t, cluster)
+			defer f.close()
+			f.mustSync()
+			f.createPods("test-master", "rev", true)
+			f.createPods("test-data", "rev", true)
+			f.rollOut("test-master", "rev")
+			f.rollOut("test-data", "rev")
+
+			// shards are reported under the name the node joined with
+			for i := range test.shards {
+				test.shards[i].Node = f.nodeName(f.statefulSet("test-data"), test.shards[i].Node)
+			}
+			f.server.Shards = test.shards
+			f.server.Indices = test.indices
+			f.updateCluster(func(cluster *esV1.Cluster) {
+				cluster.Spec.Size = 2
+			})
+			f.mustSync()
+
+			if replicas := *f.statefulSet("test-data").Spec.Replicas; replicas != test.expectedReplicas {
+				t.Errorf("expected %d data replicas, got %d", test.expectedReplicas, replicas)
+			}
+			if excluded := f.persistentSetting(excludeSetting); excluded != test.expectedExcluded {
+				t.Errorf("expected nodes %v excluded, got %v", test.expectedExcluded, excluded)
+			}
+			condition := getCondition(&f.cluster().Status, esV1.ClusterScaleDownBlocked)
+			if blocked := condition.Status == corev1.ConditionTrue; blocked != test.expectedBlocked {
+				t.Errorf("expected scale down blocked %v, got %+v", test.expectedBlocked, condition)
+			}
+		})
+	}
+}
+
+func TestScaleDownClearsExclusion(t *testing.T) {
+	cluster := newTestCluster()
+	cluster.Spec.Size = 3
+	f := newFixture(t, cluster)
+	defer f.close()
+	f.mustSync()
+	f.createPods("test-master", "rev", true)
+	f.createPods("test-data", "rev", true)
+	f.rollOut("test-master", "rev")
+	f.rollOut("test-data", "rev")
+
+	f.server.Shards = []esclient.Shard{{Index: "logs", Shard: "0", Node: f.nodeName(f.statefulSet("test-data"), "test-data-2")}}
+	f.updateCluster(func(cluster *esV1.Cluster) {
+		cluster.Spec.Size = 2
+	})
+	f.mustSync()
+	if excluded := f.cluster().Status.ExcludedNodes; !reflect.DeepEqual(excluded, []string{"test-data-2"}) {
+		t.Fatalf("expected test-data-2 excluded, got %v", excluded)
+	}
+
+	f.server.Shards = nil
+	f.mustSync()
+	if replicas := *f.statefulSet("test-data").Spec.Replicas; replicas != 2 {
+		t.Fatalf("expected 2 data replicas, got %d", replicas)
+	}
+
+	// the exclusion is kept until the node is gone
+	f.mustSync()
+	if excluded := f.persistentSetting(excludeSetting); excluded != "test-data-2" {
+		t.Errorf("expected test-data-2 excluded while it shuts down, got %v", excluded)
+	}
+
+	f.rollOut("test-data", "rev")
+	f.mustSync()
+	if excluded := f.persistentSetting(excludeSetting); excluded != nil {
+		t.Errorf("expected exclusion cleared, got %v", excluded)
+	}
+	if excluded := f.cluster().Status.ExcludedNodes; len(excluded) != 0 {
+		t.Errorf("expected no excluded nodes in status, got %v", excluded)
+	}
+}
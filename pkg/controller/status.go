@@ -0,0 +1,249 @@
+package controller
+
+import (
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	v1beta2 "k8s.io/api/apps/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ReasonReconcileFailed is used as the condition reason when a sync
+	// returns an error
+	ReasonReconcileFailed = "ReconcileFailed"
+
+	// ReasonNodesNotReady is used as the condition reason when fewer nodes
+	// are ready than desired
+	ReasonNodesNotReady = "NodesNotReady"
+
+	// ReasonNodesReady is used as the condition reason when all desired nodes
+	// are ready
+	ReasonNodesReady = "NodesReady"
+
+	// ReasonInvalidSpec is used as the condition reason when the spec of a
+	// cluster fails validation
+	ReasonInvalidSpec = "InvalidSpec"
+
+	// ReasonUnsupportedVersion is used as the condition reason when the spec
+	// requests an elasticsearch version the controller cannot run
+	ReasonUnsupportedVersion = "UnsupportedVersion"
+
+	// ReasonSupportedVersion is used as the condition reason when the
+	// requested elasticsearch version is supported
+	ReasonSupportedVersion = "SupportedVersion"
+
+	// ReasonInsufficientCapacity is used as the condition reason when the
+	// remaining data nodes cannot hold every replica
+	ReasonInsufficientCapacity = "InsufficientCapacity"
+
+	// ReasonSufficientCapacity is used as the condition reason when no scale
+	// down is blocked
+	ReasonSufficientCapacity = "SufficientCapacity"
+
+	// ReasonRollingOut is used as the condition reason while a workload has
+	// not yet observed or finished rolling out its latest spec
+	ReasonRollingOut = "RollingOut"
+)
+
+// observedState collects what sync saw of the child workloads of a cluster
+type observedState struct {
+	roles        []esV1.RoleStatus
+	progressing  bool
+	health       esV1.ClusterHealth
+	statefulSets map[string]*v1beta2.StatefulSet
+	upgrade      *esV1.UpgradeStatus
+	// requeue is set when the cluster should be synced again after
+	// requeueInterval as it is waiting on elasticsearch
+	requeue bool
+
+	invalidSpec        error
+	unsupportedVersion error
+
+	endpoint string
+
+	bootstrapped           bool
+	votingConfigExclusions []string
+	mastersScalingDown     bool
+
+	// dataReplicas is the number of data nodes currently running
+	dataReplicas int32
+
+	leavingNodes     []string
+	excludedNodes    []string
+	dataScalingDown  bool
+	scaleDownBlocked string
+}
+
+// observeStatefulSet adds the desired and ready counts of the statefulset of a
+// pool to each of the roles of the pool, and records whether it is still
+// rolling out
+func (o *observedState) observeStatefulSet(pool *esV1.NodePool, statefulSet *v1beta2.StatefulSet) {
+	o.statefulSets[pool.Name] = statefulSet
+	desired := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		desired = *statefulSet.Spec.Replicas
+	}
+	for _, role := range pool.Roles {
+		status := o.role(string(role))
+		status.Desired += desired
+		status.Ready += statefulSet.Status.ReadyReplicas
+	}
+	if statefulSet.Status.ObservedGeneration < statefulSet.Generation || statefulSet.Status.UpdatedReplicas < desired {
+		o.progressing = true
+	}
+	if pool.HasRole(esV1.NodeRoleMaster) && statefulSet.Status.Replicas > desired {
+		o.mastersScalingDown = true
+	}
+	if pool.HasRole(esV1.NodeRoleData) {
+		o.dataReplicas += statefulSet.Status.Replicas
+		if statefulSet.Status.Replicas > desired {
+			o.dataScalingDown = true
+		}
+	}
+}
+
+// anyReady returns whether any node of the cluster is ready
+func (o *observedState) anyReady() bool {
+	for _, role := range o.roles {
+		if role.Ready > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// role returns the status of role, adding it if not already present
+func (o *observedState) role(role string) *esV1.RoleStatus {
+	for i := range o.roles {
+		if o.roles[i].Role == role {
+			return &o.roles[i]
+		}
+	}
+	o.roles = append(o.roles, esV1.RoleStatus{Role: role})
+	return &o.roles[len(o.roles)-1]
+}
+
+// newClusterStatus computes the status of a cluster from the state observed
+// during sync. syncErr is the error returned by the reconcile, if any.
+func newClusterStatus(cluster *esV1.Cluster, observed *observedState, syncErr error) esV1.ClusterStatus {
+	status := *cluster.Status.DeepCopy()
+	status.ObservedGeneration = cluster.Generation
+	status.Roles = observed.roles
+	roles := observed.roles
+	progressing := observed.progressing
+	status.Health = observed.health
+	if status.Health == "" {
+		status.Health = esV1.ClusterHealthUnknown
+	}
+	status.Bootstrapped = observed.bootstrapped
+	status.VotingConfigExclusions = observed.votingConfigExclusions
+	status.Upgrade = observed.upgrade
+	status.ExcludedNodes = observed.excludedNodes
+	if observed.endpoint != "" {
+		status.Endpoint = observed.endpoint
+	}
+	status.Replicas = observed.dataReplicas
+	status.Selector = dataSelector(cluster).String()
+
+	ready := len(roles) > 0
+	anyReady := false
+	for _, role := range roles {
+		if role.Ready < role.Desired {
+			ready = false
+		}
+		if role.Ready > 0 {
+			anyReady = true
+		}
+	}
+
+	if ready {
+		setCondition(&status, cluster.Generation, esV1.ClusterReady, corev1.ConditionTrue, ReasonNodesReady, "All nodes are ready")
+	} else {
+		setCondition(&status, cluster.Generation, esV1.ClusterReady, corev1.ConditionFalse, ReasonNodesNotReady, "Not all nodes are ready")
+	}
+
+	if progressing {
+		setCondition(&status, cluster.Generation, esV1.ClusterProgressing, corev1.ConditionTrue, ReasonRollingOut, "Child resources are rolling out")
+	} else {
+		setCondition(&status, cluster.Generation, esV1.ClusterProgressing, corev1.ConditionFalse, ReasonNodesReady, "Child resources are up to date")
+	}
+
+	switch {
+	case observed.invalidSpec != nil:
+		setCondition(&status, cluster.Generation, esV1.ClusterDegradedCondition, corev1.ConditionTrue, ReasonInvalidSpec, observed.invalidSpec.Error())
+	case syncErr != nil:
+		setCondition(&status, cluster.Generation, esV1.ClusterDegradedCondition, corev1.ConditionTrue, ReasonReconcileFailed, syncErr.Error())
+	case !ready && !progressing:
+		setCondition(&status, cluster.Generation, esV1.ClusterDegradedCondition, corev1.ConditionTrue, ReasonNodesNotReady, "Not all nodes are ready")
+	default:
+		setCondition(&status, cluster.Generation, esV1.ClusterDegradedCondition, corev1.ConditionFalse, ReasonNodesReady, "")
+	}
+
+	if observed.unsupportedVersion != nil {
+		setCondition(&status, cluster.Generation, esV1.ClusterVersionSupported, corev1.ConditionFalse, ReasonUnsupportedVersion, observed.unsupportedVersion.Error())
+	} else {
+		setCondition(&status, cluster.Generation, esV1.ClusterVersionSupported, corev1.ConditionTrue, ReasonSupportedVersion, "")
+	}
+
+	if observed.scaleDownBlocked != "" {
+		setCondition(&status, cluster.Generation, esV1.ClusterScaleDownBlocked, corev1.ConditionTrue, ReasonInsufficientCapacity, observed.scaleDownBlocked)
+	} else {
+		setCondition(&status, cluster.Generation, esV1.ClusterScaleDownBlocked, corev1.ConditionFalse, ReasonSufficientCapacity, "")
+	}
+
+	switch {
+	case ready:
+		status.Phase = esV1.ClusterRunning
+	case anyReady && getCondition(&status, esV1.ClusterDegradedCondition).Status == corev1.ConditionTrue:
+		status.Phase = esV1.ClusterDegraded
+	default:
+		status.Phase = esV1.ClusterPending
+	}
+
+	return status
+}
+
+func getCondition(status *esV1.ClusterStatus, conditionType esV1.ClusterConditionType) *esV1.ClusterCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == conditionType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// setCondition adds or updates a condition, only moving the transition time
+// when the status of the condition changes
+func setCondition(status *esV1.ClusterStatus, generation int64, conditionType esV1.ClusterConditionType, conditionStatus corev1.ConditionStatus, reason, message string) {
+	condition := esV1.ClusterCondition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		ObservedGeneration: generation,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+
+	if existing := getCondition(status, conditionType); existing != nil {
+		if existing.Status == conditionStatus {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		*existing = condition
+		return
+	}
+	status.Conditions = append(status.Conditions, condition)
+}
+
+// updateStatus writes status to the status subresource of the cluster if it
+// differs from what is already recorded
+func (c *Controller) updateStatus(cluster *esV1.Cluster, status esV1.ClusterStatus) error {
+	if equality.Semantic.DeepEqual(cluster.Status, status) {
+		return nil
+	}
+
+	clusterCopy := cluster.DeepCopy()
+	clusterCopy.Status = status
+	_, err := c.esclientset.EsV1().Clusters(cluster.Namespace).UpdateStatus(clusterCopy)
+	return err
+}
@@ -0,0 +1,247 @@
+package controller
+
+import (
+	"sort"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/esclient"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	v1beta2 "k8s.io/api/apps/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// SuccessRestarted is used as part of the Event 'reason' when a node is
+	// restarted onto the latest pod template
+	SuccessRestarted = "Restarted"
+
+	// MessageNodeRestarting is the message used for events when a node is
+	// restarted as part of a rolling upgrade
+	MessageNodeRestarting = "Restarting node %q onto the latest pod template"
+)
+
+// syncUpgrade restarts the nodes whose pods run an outdated template one at a
+// time. The statefulsets use the OnDelete strategy, so nothing is restarted
+// unless the controller deletes the pod. The node being restarted is recorded
+// in the cluster status before anything is changed, then shard allocation is
+// limited to primaries and indices flushed before the pod is deleted.
+// Allocation is re-enabled once the replacement node has rejoined, and the
+// controller waits for the cluster to return to yellow or green before moving
+// on. Each step is written to the status before the next one is taken, so an
+// upgrade interrupted by an operator restart resumes where it left off.
+func (c *Controller) syncUpgrade(logger log.Logger, cluster *esV1.Cluster, pools []esV1.NodePool, v version, observed *observedState) error {
+	if observed.upgrade != nil {
+		observed.requeue = true
+		return c.continueUpgrade(logger, cluster, v, observed)
+	}
+
+	outdated, err := c.outdatedPods(cluster, pools, observed)
+	if err != nil {
+		return err
+	}
+
+	if len(outdated) == 0 {
+		return nil
+	}
+	observed.requeue = true
+
+	// nodes that are not ready are not holding any shards the cluster can
+	// use, so they are restarted without touching allocation. This also lets
+	// a spec change fix nodes that are failing to start, which may never
+	// rejoin the cluster, so only the deletion is done one at a time.
+	for _, pod := range outdated {
+		if isPodReady(pod) {
+			continue
+		}
+		terminating, err := c.podsTerminating(cluster)
+		if err != nil || terminating {
+			return err
+		}
+		return c.restartPod(cluster, pod)
+	}
+
+	if !isHealthy(observed.health) {
+		logger.Infof("Waiting for cluster %s to be healthy before restarting %s", cluster.Name, outdated[0].Name)
+		return nil
+	}
+
+	// the pod is only deleted on the next sync, once the status naming it has
+	// been written
+	observed.upgrade = &esV1.UpgradeStatus{
+		Pod:   outdated[0].Name,
+		Phase: esV1.UpgradeRestarting,
+	}
+	return nil
+}
+
+// continueUpgrade takes the next step of the restart of a single node. Steps
+// that wait on elasticsearch or that change its allocation end the sync, so
+// the phase they move to is written before anything else is done.
+func (c *Controller) continueUpgrade(logger log.Logger, cluster *esV1.Cluster, v version, observed *observedState) error {
+	upgrade := observed.upgrade
+
+	switch upgrade.Phase {
+	case esV1.UpgradeRestarting:
+		pod, err := c.podLister.Pods(cluster.Namespace).Get(upgrade.Pod)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		// the pod is gone or already replaced when resuming after the
+		// delete, in which case allocation is still limited to primaries
+		if err == nil && pod.DeletionTimestamp == nil && isPodOutdated(pod, observed) {
+			if !isHealthy(observed.health) {
+				logger.Infof("Waiting for cluster %s to be healthy before restarting %s", cluster.Name, pod.Name)
+				return nil
+			}
+			client, err := c.esClient(cluster)
+			if err != nil {
+				return err
+			}
+			if err := client.SetAllocation("primaries"); err != nil {
+				return err
+			}
+			if err := client.SyncedFlush(v.Major, v.Minor); err != nil {
+				return err
+			}
+			if err := c.restartPod(cluster, pod); err != nil {
+				return err
+			}
+		}
+		upgrade.Phase = esV1.UpgradeWaitingForNode
+
+	case esV1.UpgradeWaitingForNode:
+		pod, err := c.podLister.Pods(cluster.Namespace).Get(upgrade.Pod)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if pod.DeletionTimestamp != nil {
+			return nil
+		}
+		if !isPodReady(pod) {
+			// the spec may have been fixed while the replacement failed to
+			// start, in which case it is replaced again at the new revision
+			if isPodOutdated(pod, observed) {
+				return c.restartPod(cluster, pod)
+			}
+			return nil
+		}
+
+		client, err := c.esClient(cluster)
+		if err != nil {
+			return err
+		}
+		nodes, err := client.Nodes()
+		if err != nil {
+			return err
+		}
+		if !hasNode(nodes, pod.Name) {
+			logger.Infof("Waiting for node %s to rejoin cluster %s", pod.Name, cluster.Name)
+			return nil
+		}
+
+		if err := client.SetAllocation(""); err != nil {
+			return err
+		}
+		// the health observed by this sync predates allocation being
+		// re-enabled, so it is only checked by the next one
+		upgrade.Phase = esV1.UpgradeWaitingForHealth
+
+	case esV1.UpgradeWaitingForHealth:
+		if !isHealthy(observed.health) {
+			return nil
+		}
+		logger.Infof("Node %s of cluster %s has been restarted", upgrade.Pod, cluster.Name)
+		observed.upgrade = nil
+	}
+
+	return nil
+}
+
+// podsTerminating returns whether any pod of the cluster is being deleted
+func (c *Controller) podsTerminating(cluster *esV1.Cluster) (bool, error) {
+	pods, err := c.podLister.Pods(cluster.Namespace).List(labels.SelectorFromSet(labels.Set{clusterLabel: cluster.Name}))
+	if err != nil {
+		return false, err
+	}
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func hasNode(nodes []esclient.Node, name string) bool {
+	for _, node := range nodes {
+		if node.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// outdatedPods returns the pods that do not run the latest template of their
+// statefulset, ordered so nodes that are not master eligible are restarted
+// first and master eligible nodes last
+func (c *Controller) outdatedPods(cluster *esV1.Cluster, pools []esV1.NodePool, observed *observedState) ([]*corev1.Pod, error) {
+	var outdated []*corev1.Pod
+	for i := range pools {
+		selector := poolSelector(cluster, &pools[i])
+		pods, err := c.podLister.Pods(cluster.Namespace).List(labels.SelectorFromSet(selector.MatchLabels))
+		if err != nil {
+			return nil, err
+		}
+		for _, pod := range pods {
+			if pod.DeletionTimestamp == nil && isPodOutdated(pod, observed) {
+				outdated = append(outdated, pod)
+			}
+		}
+	}
+
+	sort.SliceStable(outdated, func(i, j int) bool {
+		iMaster := outdated[i].Labels[roleLabel(esV1.NodeRoleMaster)] == "true"
+		jMaster := outdated[j].Labels[roleLabel(esV1.NodeRoleMaster)] == "true"
+		if iMaster != jMaster {
+			return jMaster
+		}
+		return outdated[i].Name > outdated[j].Name
+	})
+	return outdated, nil
+}
+
+func (c *Controller) restartPod(cluster *esV1.Cluster, pod *corev1.Pod) error {
+	c.recorder.Eventf(cluster, corev1.EventTypeNormal, SuccessRestarted, MessageNodeRestarting, pod.Name)
+	err := c.kubeclientset.CoreV1().Pods(pod.Namespace).Delete(pod.Name, nil)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// isPodOutdated returns whether pod runs an older template than the latest
+// revision of its statefulset
+func isPodOutdated(pod *corev1.Pod, observed *observedState) bool {
+	statefulSet, ok := observed.statefulSets[pod.Labels[poolLabel]]
+	if !ok || statefulSet.Status.UpdateRevision == "" {
+		return false
+	}
+	return pod.Labels[v1beta2.StatefulSetRevisionLabel] != statefulSet.Status.UpdateRevision
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func isHealthy(health esV1.ClusterHealth) bool {
+	return health == esV1.ClusterHealthGreen || health == esV1.ClusterHealthYellow
+}
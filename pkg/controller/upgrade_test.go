@@ -0,0 +1,240 @@
+package controller
+
+import (
+	"testing"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const allocationSetting = "cluster.routing.allocation.enable"
+
+// newUpgradeFixture returns a fixture whose nodes are all ready at revision
+// old while their statefulsets are at revision new
+func newUpgradeFixture(t *testing.T) *fixture {
+	f := newFixture(t, newTestCluster())
+	f.mustSync()
+	for _, name := range []string{"test-master", "test-data"} {
+		f.createPods(name, "old", true)
+		f.rollOut(name, "new")
+	}
+	return f
+}
+
+func (f *fixture) upgrade() *esV1.UpgradeStatus {
+	return f.cluster().Status.Upgrade
+}
+
+func (f *fixture) expectUpgrade(pod string, phase esV1.UpgradePhase) {
+	f.t.Helper()
+	upgrade := f.upgrade()
+	if upgrade == nil || upgrade.Pod != pod || upgrade.Phase != phase {
+		f.t.Fatalf("expected upgrade of %s in phase %s, got %+v", pod, phase, upgrade)
+	}
+}
+
+func TestUpgradePhases(t *testing.T) {
+	f := newUpgradeFixture(t)
+	defer f.close()
+
+	// the node is recorded before anything is changed
+	f.mustSync()
+	f.expectUpgrade("test-data-1", esV1.UpgradeRestarting)
+	if !f.podExists("test-data-1") || f.persistentSetting(allocationSetting) != nil {
+		t.Fatalf("expected nothing changed before the upgrade is recorded")
+	}
+
+	f.mustSync()
+	f.expectUpgrade("test-data-1", esV1.UpgradeWaitingForNode)
+	if f.podExists("test-data-1") {
+		t.Errorf("expected pod test-data-1 deleted")
+	}
+	if setting := f.persistentSetting(allocationSetting); setting != "primaries" {
+		t.Errorf("expected allocation limited to primaries, got %v", setting)
+	}
+
+	// waits for the replacement pod
+	f.mustSync()
+	f.expectUpgrade("test-data-1", esV1.UpgradeWaitingForNode)
+
+	f.createPod(f.statefulSet("test-data"), "test-data-1", "new", true)
+	f.mustSync()
+	f.expectUpgrade("test-data-1", esV1.UpgradeWaitingForHealth)
+	if setting := f.persistentSetting(allocationSetting); setting != nil {
+		t.Errorf("expected allocation re-enabled, got %v", setting)
+	}
+
+	f.mustSync()
+	if upgrade := f.upgrade(); upgrade != nil {
+		t.Fatalf("expected upgrade of test-data-1 finished, got %+v", upgrade)
+	}
+
+	// master eligible nodes are restarted last
+	f.mustSync()
+	f.expectUpgrade("test-data-0", esV1.UpgradeRestarting)
+}
+
+func TestUpgradeWaitsForHealth(t *testing.T) {
+	f := newUpgradeFixture(t)
+	defer f.close()
+	f.server.Health.Status = "red"
+
+	f.mustSync()
+	if upgrade := f.upgrade(); upgrade != nil {
+		t.Fatalf("expected no upgrade of a red cluster, got %+v", upgrade)
+	}
+
+	f.server.Health.Status = "green"
+	f.mustSync()
+	f.server.Health.Status = "red"
+	f.mustSync()
+	f.expectUpgrade("test-data-1", esV1.UpgradeRestarting)
+	if !f.podExists("test-data-1") {
+		t.Errorf("expected pod test-data-1 kept while the cluster is red")
+	}
+}
+
+// TestUpgradeResume starts a controller on a cluster whose status records an
+// upgrade interrupted at each step
+func TestUpgradeResume(t *testing.T) {
+	tests := []struct {
+		name  string
+		phase esV1.UpgradePhase
+		// replaced is whether the pod has been replaced at the new revision
+		replaced   bool
+		allocation interface{}
+		health     string
+
+		expectedPhase      esV1.UpgradePhase
+		expectedDeleted    bool
+		expectedAllocation interface{}
+	}{
+		{"crashed before deleting the pod", esV1.UpgradeRestarting, false, "primaries", "green",
+			esV1.UpgradeWaitingForNode, true, "primaries"},
+		{"crashed after deleting the pod", esV1.UpgradeRestarting, true, "primaries", "yellow",
+			esV1.UpgradeWaitingForNode, false, "primaries"},
+		{"node rejoined", esV1.UpgradeWaitingForNode, true, "primaries", "yellow",
+			esV1.UpgradeWaitingForHealth, false, nil},
+		{"crashed after re-enabling allocation", esV1.UpgradeWaitingForNode, true, nil, "yellow",
+			esV1.UpgradeWaitingForHealth, false, nil},
+		{"waiting for health", esV1.UpgradeWaitingForHealth, true, nil, "red",
+			esV1.UpgradeWaitingForHealth, false, nil},
+		{"healthy", esV1.UpgradeWaitingForHealth, true, nil, "green",
+			"", false, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newUpgradeFixture(t)
+			defer f.close()
+
+			if test.replaced {
+				if err := f.kubeclient.CoreV1().Pods(testNamespace).Delete("test-data-1", &metav1.DeleteOptions{}); err != nil {
+					t.Fatal(err)
+				}
+				f.createPod(f.statefulSet("test-data"), "test-data-1", "new", true)
+			}
+			if test.allocation != nil {
+				f.server.Settings.Persistent[allocationSetting] = test.allocation
+			}
+			f.server.Health.Status = test.health
+			f.updateCluster(func(cluster *esV1.Cluster) {
+				cluster.Status.Upgrade = &esV1.UpgradeStatus{Pod: "test-data-1", Phase: test.phase}
+			})
+
+			f.mustSync()
+
+			if test.expectedPhase == "" {
+				if upgrade := f.upgrade(); upgrade != nil {
+					t.Errorf("expected upgrade finished, got %+v", upgrade)
+				}
+			} else {
+				f.expectUpgrade("test-data-1", test.expectedPhase)
+			}
+			if deleted := !f.podExists("test-data-1"); deleted != test.expectedDeleted {
+				t.Errorf("expected pod deleted %v, got %v", test.expectedDeleted, deleted)
+			}
+			if setting := f.persistentSetting(allocationSetting); setting != test.expectedAllocation {
+				t.Errorf("expected allocation %v, got %v", test.expectedAllocation, setting)
+			}
+		})
+	}
+}
+
+func TestUpgradeRestartsNotReadyPodsOneAtATime(t *testing.T) {
+	f := newFixture(t, newTestCluster())
+	defer f.close()
+	f.mustSync()
+	f.createPods("test-master", "new", true)
+	f.createPods("test-data", "old", false)
+	f.rollOut("test-master", "new")
+	f.rollOut("test-data", "new")
+
+	f.mustSync()
+	if f.podExists("test-data-1") || !f.podExists("test-data-0") {
+		t.Fatalf("expected only pod test-data-1 restarted")
+	}
+	if upgrade := f.upgrade(); upgrade != nil {
+		t.Errorf("expected pods that are not ready restarted without an upgrade, got %+v", upgrade)
+	}
+
+	// the replacement is still terminating
+	terminating := metav1.Now()
+	f.createPod(f.statefulSet("test-data"), "test-data-1", "new", false)
+	pod, err := f.kubeclient.CoreV1().Pods(testNamespace).Get("test-data-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod.DeletionTimestamp = &terminating
+	if _, err := f.kubeclient.CoreV1().Pods(testNamespace).Update(pod); err != nil {
+		t.Fatal(err)
+	}
+	f.mustSync()
+	if !f.podExists("test-data-0") {
+		t.Fatalf("expected pod test-data-0 kept while another pod terminates")
+	}
+
+	if err := f.kubeclient.CoreV1().Pods(testNamespace).Delete("test-data-1", &metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	f.mustSync()
+	if f.podExists("test-data-0") {
+		t.Errorf("expected pod test-data-0 restarted")
+	}
+}
+
+// TestUpgradeSpecFixed fixes the spec of a cluster whose restarted node fails
+// to start, which would otherwise leave allocation limited to primaries
+func TestUpgradeSpecFixed(t *testing.T) {
+	f := newUpgradeFixture(t)
+	defer f.close()
+	f.mustSync()
+	f.mustSync()
+	f.expectUpgrade("test-data-1", esV1.UpgradeWaitingForNode)
+
+	// the replacement crash loops
+	f.createPod(f.statefulSet("test-data"), "test-data-1", "new", false)
+	f.mustSync()
+	f.expectUpgrade("test-data-1", esV1.UpgradeWaitingForNode)
+	if !f.podExists("test-data-1") {
+		t.Fatalf("expected pod test-data-1 kept while it runs the latest revision")
+	}
+
+	f.updateCluster(func(cluster *esV1.Cluster) {
+		cluster.Spec.Config = map[string]string{"indices.memory.index_buffer_size": "20%"}
+	})
+	f.mustSync()
+	f.rollOut("test-data", "fixed")
+	f.mustSync()
+	f.expectUpgrade("test-data-1", esV1.UpgradeWaitingForNode)
+	if f.podExists("test-data-1") {
+		t.Fatalf("expected pod test-data-1 replaced at the fixed revision")
+	}
+
+	f.createPod(f.statefulSet("test-data"), "test-data-1", "fixed", true)
+	f.mustSync()
+	f.expectUpgrade("test-data-1", esV1.UpgradeWaitingForHealth)
+	if setting := f.persistentSetting(allocationSetting); setting != nil {
+		t.Errorf("expected allocation re-enabled, got %v", setting)
+	}
+}
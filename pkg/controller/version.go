@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"fmt"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/validation"
+)
+
+var (
+	// minimumVersion is the oldest supported elasticsearch version
+	minimumVersion = validation.Version{Major: 6}
+	// maximumVersion is the first elasticsearch version that is not supported
+	maximumVersion = validation.Version{Major: 8}
+)
+
+// version is the elasticsearch version of a cluster, parsed by the rules of
+// the validation package
+type version struct {
+	validation.Version
+}
+
+// clusterVersion returns the elasticsearch version of the cluster
+func clusterVersion(cluster *esV1.Cluster) (version, error) {
+	v, err := validation.ParseVersion(cluster.Spec.Version)
+	if err != nil {
+		return version{}, fmt.Errorf("invalid version %q, %v", cluster.Spec.Version, err)
+	}
+	return version{v}, nil
+}
+
+// usesZen2 returns whether nodes discover each other with the cluster
+// coordination subsystem introduced in 7.0
+func (v version) usesZen2() bool {
+	return v.Major >= 7
+}
+
+// checkSupported returns an error if the operator cannot run version v
+func (v version) checkSupported() error {
+	if v.LessThan(minimumVersion) || !v.LessThan(maximumVersion) {
+		return fmt.Errorf("elasticsearch version %v is not supported, versions from %v up to but excluding %v are supported", v, minimumVersion, maximumVersion)
+	}
+	return nil
+}
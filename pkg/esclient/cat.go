@@ -0,0 +1,56 @@
+package esclient
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type Shard struct {
+	Index  string `json:"index"`
+	Shard  string `json:"shard"`
+	PriRep string `json:"prirep"`
+	State  string `json:"state"`
+	Node   string `json:"node"`
+	// RelocatingNode is the node a RELOCATING shard is moving to, Node being
+	// the node it is moving off
+	RelocatingNode string `json:"-"`
+}
+
+// Shards returns every shard copy in the cluster and the node holding it
+func (c *Client) Shards() ([]Shard, error) {
+	var shards []Shard
+	query := url.Values{"format": {"json"}, "h": {"index,shard,prirep,state,node"}}
+	if err := c.do(http.MethodGet, "/_cat/shards", query, nil, &shards); err != nil {
+		return nil, err
+	}
+	// the node of a relocating shard is reported as "source -> address id
+	// target"
+	for i := range shards {
+		parts := strings.SplitN(shards[i].Node, " -> ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		shards[i].Node = parts[0]
+		if fields := strings.Fields(parts[1]); len(fields) > 0 {
+			shards[i].RelocatingNode = fields[len(fields)-1]
+		}
+	}
+	return shards, nil
+}
+
+type Index struct {
+	Index    string `json:"index"`
+	Health   string `json:"health"`
+	Replicas string `json:"rep"`
+}
+
+// Indices returns every index in the cluster with its number of replicas
+func (c *Client) Indices() ([]Index, error) {
+	var indices []Index
+	query := url.Values{"format": {"json"}, "h": {"index,health,rep"}}
+	if err := c.do(http.MethodGet, "/_cat/indices", query, nil, &indices); err != nil {
+		return nil, err
+	}
+	return indices, nil
+}
@@ -0,0 +1,203 @@
+// Package esclient is a small client for the parts of the elasticsearch REST
+// api used by the controller.
+package esclient
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// idleConnTimeout is how long a keep-alive connection to a cluster is kept
+// unused before it is closed
+const idleConnTimeout = 90 * time.Second
+
+// Interface is the elasticsearch api used by the controller
+type Interface interface {
+	Health() (*ClusterHealth, error)
+
+	GetClusterSettings() (*ClusterSettings, error)
+	PutClusterSettings(settings ClusterSettings) error
+	SetAllocation(enable string) error
+	ExcludeNodes(nodeNames []string) error
+	AllocationExplain(request *AllocationExplainRequest) (*AllocationExplanation, error)
+
+	AddVotingConfigExclusions(major, minor int, nodeNames []string) error
+	ClearVotingConfigExclusions() error
+
+	Nodes() ([]Node, error)
+	SyncedFlush(major, minor int) error
+
+	Shards() ([]Shard, error)
+	Indices() ([]Index, error)
+
+	PutSnapshotRepository(name string, repository SnapshotRepository) error
+	CreateSnapshot(repository, name string) error
+	GetSnapshot(repository, name string) (*Snapshot, error)
+}
+
+// Config describes how to reach a cluster
+type Config struct {
+	// URL of the cluster, such as https://example-master-service.default.svc:9200
+	URL string
+	// Username and Password are sent with basic auth when Username is set
+	Username string
+	Password string
+	// CACert is a PEM encoded certificate authority used to verify the
+	// certificate of the cluster, the system roots are used when empty
+	CACert []byte
+	// InsecureSkipVerify disables verification of the cluster certificate
+	InsecureSkipVerify bool
+	// Timeout of each request, defaults to 10 seconds
+	Timeout time.Duration
+}
+
+// Client talks to the REST api of a single elasticsearch cluster
+type Client struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+var _ Interface = &Client{}
+
+// ServiceURL returns the url of the REST api served by a kubernetes service
+func ServiceURL(scheme, service, namespace string, port int) string {
+	return fmt.Sprintf("%v://%v.%v.svc:%d", scheme, service, namespace, port)
+}
+
+// New returns a client for the cluster described by config
+func New(config Config) (*Client, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("elasticsearch url is required")
+	}
+
+	transport, err := sharedTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	return &Client{
+		baseURL:  strings.TrimSuffix(config.URL, "/"),
+		username: config.Username,
+		password: config.Password,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+		},
+	}, nil
+}
+
+// transports are shared by the clients with the same TLS settings, as a
+// client is built for every sync and each transport keeps its own pool of
+// connections
+var transports = struct {
+	sync.Mutex
+	byKey map[transportKey]*http.Transport
+}{byKey: map[transportKey]*http.Transport{}}
+
+type transportKey struct {
+	caCert             string
+	insecureSkipVerify bool
+}
+
+func sharedTransport(config Config) (*http.Transport, error) {
+	key := transportKey{caCert: string(config.CACert), insecureSkipVerify: config.InsecureSkipVerify}
+
+	transports.Lock()
+	defer transports.Unlock()
+	if transport, ok := transports.byKey[key]; ok {
+		return transport, nil
+	}
+
+	transport := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify},
+		IdleConnTimeout: idleConnTimeout,
+	}
+	if len(config.CACert) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(config.CACert) {
+			return nil, fmt.Errorf("no certificates found in elasticsearch ca")
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	transports.byKey[key] = transport
+	return transport, nil
+}
+
+// Error is returned when elasticsearch responds with a non 2xx status
+type Error struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("elasticsearch responded with status %d: %s", e.StatusCode, e.Body)
+}
+
+// IsNotFound returns whether err is a 404 response from elasticsearch
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+func (c *Client) do(method, path string, query url.Values, body interface{}, result interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return &Error{StatusCode: resp.StatusCode, Body: string(b)}
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
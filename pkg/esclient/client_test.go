@@ -0,0 +1,249 @@
+package esclient_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/matt-tyler/elasticsearch-operator/pkg/esclient"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/esclient/fake"
+)
+
+func TestHealth(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+	server.Health.Status = "yellow"
+
+	health, err := server.Client().Health()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.Status != "yellow" || health.ClusterName != "fake" {
+		t.Errorf("expected yellow health of cluster fake, got %+v", health)
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+	server.Username = "elastic"
+	server.Password = "changeme"
+
+	if _, err := server.Client().Health(); err != nil {
+		t.Errorf("expected request with credentials to succeed, got %v", err)
+	}
+
+	config := server.Config()
+	config.Password = "wrong"
+	client, err := esclient.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Health()
+	if e, ok := err.(*esclient.Error); !ok || e.StatusCode != 401 {
+		t.Errorf("expected 401 with wrong password, got %v", err)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	server := fake.NewTLSServer()
+	defer server.Close()
+
+	if _, err := server.Client().Health(); err != nil {
+		t.Errorf("expected request verified by ca to succeed, got %v", err)
+	}
+
+	config := server.Config()
+	config.CACert = nil
+	client, err := esclient.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Health(); err == nil {
+		t.Errorf("expected request to fail without the ca")
+	}
+
+	config.InsecureSkipVerify = true
+	client, err = esclient.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Health(); err != nil {
+		t.Errorf("expected insecure request to succeed, got %v", err)
+	}
+}
+
+func TestInvalidCACert(t *testing.T) {
+	_, err := esclient.New(esclient.Config{URL: "https://localhost:9200", CACert: []byte("not a certificate")})
+	if err == nil {
+		t.Errorf("expected error for a ca without certificates")
+	}
+}
+
+func TestAllocationSettings(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	if err := client.SetAllocation("primaries"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ExcludeNodes([]string{"test-data-1", "test-data-2"}); err != nil {
+		t.Fatal(err)
+	}
+	settings, err := client.GetClusterSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"cluster.routing.allocation.enable":        "primaries",
+		"cluster.routing.allocation.exclude._name": "test-data-1,test-data-2",
+	}
+	if !reflect.DeepEqual(settings.Persistent, expected) {
+		t.Errorf("expected persistent settings %v, got %v", expected, settings.Persistent)
+	}
+
+	if err := client.SetAllocation(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ExcludeNodes(nil); err != nil {
+		t.Fatal(err)
+	}
+	if settings, err = client.GetClusterSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if len(settings.Persistent) != 0 {
+		t.Errorf("expected settings reset, got %v", settings.Persistent)
+	}
+}
+
+func TestVotingConfigExclusions(t *testing.T) {
+	tests := []struct {
+		major, minor int
+		path         string
+	}{
+		{7, 0, "POST /_cluster/voting_config_exclusions/test-master-2"},
+		{7, 8, "POST /_cluster/voting_config_exclusions"},
+	}
+
+	for _, test := range tests {
+		server := fake.NewServer()
+		client := server.Client()
+		if err := client.AddVotingConfigExclusions(test.major, test.minor, []string{"test-master-2"}); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(server.VotingConfigExclusions, []string{"test-master-2"}) {
+			t.Errorf("%d.%d: expected test-master-2 excluded, got %v", test.major, test.minor, server.VotingConfigExclusions)
+		}
+		if server.Requests[0] != test.path {
+			t.Errorf("%d.%d: expected request %s, got %s", test.major, test.minor, test.path, server.Requests[0])
+		}
+
+		if err := client.ClearVotingConfigExclusions(); err != nil {
+			t.Fatal(err)
+		}
+		if len(server.VotingConfigExclusions) != 0 {
+			t.Errorf("%d.%d: expected exclusions cleared, got %v", test.major, test.minor, server.VotingConfigExclusions)
+		}
+		server.Close()
+	}
+}
+
+func TestNodes(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+	server.Nodes = []esclient.Node{{Name: "test-data-1"}, {Name: "test-data-0"}}
+
+	nodes, err := server.Client().Nodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 || nodes[0].Name != "test-data-0" || nodes[1].Name != "test-data-1" {
+		t.Errorf("expected nodes ordered by name, got %+v", nodes)
+	}
+	if nodes[0].ID == "" {
+		t.Errorf("expected node ids set from the response")
+	}
+}
+
+func TestSyncedFlush(t *testing.T) {
+	tests := []struct {
+		major, minor int
+		path         string
+	}{
+		{6, 8, "POST /_flush/synced"},
+		{7, 6, "POST /_flush"},
+	}
+
+	for _, test := range tests {
+		server := fake.NewServer()
+		if err := server.Client().SyncedFlush(test.major, test.minor); err != nil {
+			t.Fatal(err)
+		}
+		if server.Requests[0] != test.path {
+			t.Errorf("%d.%d: expected request %s, got %s", test.major, test.minor, test.path, server.Requests[0])
+		}
+		server.Close()
+	}
+}
+
+func TestCat(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+	server.Shards = []esclient.Shard{{Index: "logs", Shard: "0", PriRep: "p", State: "STARTED", Node: "test-data-0"}}
+	server.Indices = []esclient.Index{{Index: "logs", Health: "green", Replicas: "1"}}
+	client := server.Client()
+
+	shards, err := client.Shards()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(shards, server.Shards) {
+		t.Errorf("expected shards %+v, got %+v", server.Shards, shards)
+	}
+
+	server.Shards = []esclient.Shard{{Index: "logs", Shard: "0", PriRep: "r", State: "RELOCATING", Node: "test-data-2 -> 10.0.0.1 Xo2Lw6fCQAqQ4a0bTsAq3Q test-data-0"}}
+	shards, err = client.Shards()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shards) != 1 || shards[0].Node != "test-data-2" || shards[0].RelocatingNode != "test-data-0" {
+		t.Errorf("expected shard relocating from test-data-2 to test-data-0, got %+v", shards)
+	}
+
+	indices, err := client.Indices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(indices, server.Indices) {
+		t.Errorf("expected indices %+v, got %+v", server.Indices, indices)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+	server.Indices = []esclient.Index{{Index: "logs"}}
+	client := server.Client()
+
+	if _, err := client.GetSnapshot("backups", "final"); !esclient.IsNotFound(err) {
+		t.Errorf("expected missing snapshot to be not found, got %v", err)
+	}
+	if err := client.CreateSnapshot("backups", "final"); !esclient.IsNotFound(err) {
+		t.Errorf("expected snapshot into a missing repository to be not found, got %v", err)
+	}
+
+	repository := esclient.SnapshotRepository{Type: "fs", Settings: map[string]interface{}{"location": "/backups"}}
+	if err := client.PutSnapshotRepository("backups", repository); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CreateSnapshot("backups", "final"); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := client.GetSnapshot("backups", "final")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.State != esclient.SnapshotSuccess || !reflect.DeepEqual(snapshot.Indices, []string{"logs"}) {
+		t.Errorf("expected successful snapshot of logs, got %+v", snapshot)
+	}
+}
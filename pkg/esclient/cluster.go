@@ -0,0 +1,126 @@
+package esclient
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type ClusterHealth struct {
+	ClusterName        string `json:"cluster_name"`
+	Status             string `json:"status"`
+	NumberOfNodes      int    `json:"number_of_nodes"`
+	NumberOfDataNodes  int    `json:"number_of_data_nodes"`
+	RelocatingShards   int    `json:"relocating_shards"`
+	InitializingShards int    `json:"initializing_shards"`
+	UnassignedShards   int    `json:"unassigned_shards"`
+}
+
+// Health returns the health of the cluster. It fails while the cluster has
+// no elected master.
+func (c *Client) Health() (*ClusterHealth, error) {
+	health := &ClusterHealth{}
+	if err := c.do(http.MethodGet, "/_cluster/health", nil, nil, health); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
+
+// ClusterSettings holds persistent and transient cluster settings. Setting a
+// key to nil resets it to its default.
+type ClusterSettings struct {
+	Persistent map[string]interface{} `json:"persistent,omitempty"`
+	Transient  map[string]interface{} `json:"transient,omitempty"`
+}
+
+// GetClusterSettings returns the dynamic settings of the cluster with flat keys
+func (c *Client) GetClusterSettings() (*ClusterSettings, error) {
+	settings := &ClusterSettings{}
+	if err := c.do(http.MethodGet, "/_cluster/settings", url.Values{"flat_settings": {"true"}}, nil, settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// PutClusterSettings updates the dynamic settings of the cluster
+func (c *Client) PutClusterSettings(settings ClusterSettings) error {
+	return c.do(http.MethodPut, "/_cluster/settings", nil, settings, nil)
+}
+
+// SetAllocation sets cluster.routing.allocation.enable, an empty value resets
+// it to its default of all
+func (c *Client) SetAllocation(enable string) error {
+	var value interface{}
+	if enable != "" {
+		value = enable
+	}
+	return c.PutClusterSettings(ClusterSettings{
+		Persistent: map[string]interface{}{
+			"cluster.routing.allocation.enable": value,
+		},
+	})
+}
+
+// ExcludeNodes stops shards being allocated to the named nodes, moving any
+// shards they hold elsewhere. An empty list clears the exclusion.
+func (c *Client) ExcludeNodes(nodeNames []string) error {
+	var value interface{}
+	if len(nodeNames) > 0 {
+		value = strings.Join(nodeNames, ",")
+	}
+	return c.PutClusterSettings(ClusterSettings{
+		Persistent: map[string]interface{}{
+			"cluster.routing.allocation.exclude._name": value,
+		},
+	})
+}
+
+// AllocationExplainRequest selects the shard to explain. An empty request
+// explains the first unassigned shard.
+type AllocationExplainRequest struct {
+	Index   string `json:"index,omitempty"`
+	Shard   *int   `json:"shard,omitempty"`
+	Primary *bool  `json:"primary,omitempty"`
+}
+
+type AllocationExplanation struct {
+	Index               string `json:"index"`
+	Shard               int    `json:"shard"`
+	Primary             bool   `json:"primary"`
+	CurrentState        string `json:"current_state"`
+	CanAllocate         string `json:"can_allocate,omitempty"`
+	CanRemainOnNode     string `json:"can_remain_on_current_node,omitempty"`
+	AllocateExplanation string `json:"allocate_explanation,omitempty"`
+	UnassignedInfo      *struct {
+		Reason string `json:"reason"`
+	} `json:"unassigned_info,omitempty"`
+}
+
+// AllocationExplain explains why a shard is or is not allocated to a node
+func (c *Client) AllocationExplain(request *AllocationExplainRequest) (*AllocationExplanation, error) {
+	var body interface{}
+	if request != nil {
+		body = request
+	}
+	explanation := &AllocationExplanation{}
+	if err := c.do(http.MethodPost, "/_cluster/allocation/explain", nil, body, explanation); err != nil {
+		return nil, err
+	}
+	return explanation, nil
+}
+
+// AddVotingConfigExclusions removes the given master eligible nodes from the
+// voting configuration so they can be shut down safely. Elasticsearch 7.8
+// moved the node names from the path to a query parameter.
+func (c *Client) AddVotingConfigExclusions(major, minor int, nodeNames []string) error {
+	names := strings.Join(nodeNames, ",")
+	if major == 7 && minor < 8 {
+		return c.do(http.MethodPost, "/_cluster/voting_config_exclusions/"+url.PathEscape(names), nil, nil, nil)
+	}
+	return c.do(http.MethodPost, "/_cluster/voting_config_exclusions", url.Values{"node_names": {names}}, nil, nil)
+}
+
+// ClearVotingConfigExclusions removes all voting configuration exclusions
+func (c *Client) ClearVotingConfigExclusions() error {
+	return c.do(http.MethodDelete, "/_cluster/voting_config_exclusions", url.Values{"wait_for_removal": {"false"}}, nil, nil)
+}
@@ -0,0 +1,265 @@
+// Package fake provides an in-process elasticsearch REST api for tests of
+// code using esclient.
+package fake
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/matt-tyler/elasticsearch-operator/pkg/esclient"
+)
+
+// Server is a fake elasticsearch cluster. Its state can be changed through
+// its fields while holding Lock, and every request it receives is recorded.
+type Server struct {
+	sync.Mutex
+
+	Health                 esclient.ClusterHealth
+	Nodes                  []esclient.Node
+	Shards                 []esclient.Shard
+	Indices                []esclient.Index
+	Settings               esclient.ClusterSettings
+	Explanation            esclient.AllocationExplanation
+	VotingConfigExclusions []string
+	Repositories           map[string]esclient.SnapshotRepository
+	// Snapshots are keyed by repository then name. Snapshots created through
+	// the api start in the state of SnapshotState, SUCCESS by default.
+	Snapshots     map[string]map[string]esclient.Snapshot
+	SnapshotState string
+
+	// Username and Password are required with basic auth when Username is set
+	Username string
+	Password string
+
+	// Requests holds the method and path of every request received
+	Requests []string
+
+	server *httptest.Server
+}
+
+// NewServer starts a fake green cluster serving plain http
+func NewServer() *Server {
+	s := newServer()
+	s.server = httptest.NewServer(s)
+	return s
+}
+
+// NewTLSServer starts a fake green cluster serving https with a self-signed
+// certificate, see CACert
+func NewTLSServer() *Server {
+	s := newServer()
+	s.server = httptest.NewTLSServer(s)
+	return s
+}
+
+func newServer() *Server {
+	return &Server{
+		Health:       esclient.ClusterHealth{ClusterName: "fake", Status: "green"},
+		Repositories: map[string]esclient.SnapshotRepository{},
+		Snapshots:    map[string]map[string]esclient.Snapshot{},
+		Settings: esclient.ClusterSettings{
+			Persistent: map[string]interface{}{},
+			Transient:  map[string]interface{}{},
+		},
+		SnapshotState: esclient.SnapshotSuccess,
+	}
+}
+
+// URL of the fake cluster
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// CACert returns the PEM encoded certificate of a server started with
+// NewTLSServer
+func (s *Server) CACert() []byte {
+	if s.server.TLS == nil || len(s.server.TLS.Certificates) == 0 {
+		return nil
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: s.server.TLS.Certificates[0].Certificate[0],
+	})
+}
+
+// Config returns a client config for the fake cluster
+func (s *Server) Config() esclient.Config {
+	return esclient.Config{
+		URL:      s.URL(),
+		Username: s.Username,
+		Password: s.Password,
+		CACert:   s.CACert(),
+	}
+}
+
+// Client returns a client of the fake cluster
+func (s *Server) Client() esclient.Interface {
+	client, err := esclient.New(s.Config())
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.Requests = append(s.Requests, r.Method+" "+r.URL.Path)
+
+	if s.Username != "" {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != s.Username || password != s.Password {
+			writeError(w, http.StatusUnauthorized, "missing authentication credentials")
+			return
+		}
+	}
+
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case path == "_cluster/health" && r.Method == http.MethodGet:
+		writeJSON(w, s.Health)
+	case path == "_cluster/settings" && r.Method == http.MethodGet:
+		writeJSON(w, s.Settings)
+	case path == "_cluster/settings" && r.Method == http.MethodPut:
+		s.putSettings(w, r)
+	case path == "_cluster/allocation/explain":
+		writeJSON(w, s.Explanation)
+	case parts[0] == "_cluster" && len(parts) > 1 && parts[1] == "voting_config_exclusions":
+		s.votingConfigExclusions(w, r, parts)
+	case path == "_nodes" && r.Method == http.MethodGet:
+		s.nodes(w)
+	case (path == "_flush" || path == "_flush/synced") && r.Method == http.MethodPost:
+		writeJSON(w, map[string]interface{}{})
+	case path == "_cat/shards":
+		writeJSON(w, s.Shards)
+	case path == "_cat/indices":
+		writeJSON(w, s.Indices)
+	case parts[0] == "_snapshot" && len(parts) == 2 && r.Method == http.MethodPut:
+		s.putRepository(w, r, parts[1])
+	case parts[0] == "_snapshot" && len(parts) == 3 && r.Method == http.MethodPut:
+		s.createSnapshot(w, parts[1], parts[2])
+	case parts[0] == "_snapshot" && len(parts) == 3 && r.Method == http.MethodGet:
+		s.getSnapshot(w, parts[1], parts[2])
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("no handler found for %v %v", r.Method, r.URL.Path))
+	}
+}
+
+func (s *Server) putSettings(w http.ResponseWriter, r *http.Request) {
+	settings := esclient.ClusterSettings{}
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	apply := func(current, update map[string]interface{}) {
+		for k, v := range update {
+			if v == nil {
+				delete(current, k)
+			} else {
+				current[k] = v
+			}
+		}
+	}
+	apply(s.Settings.Persistent, settings.Persistent)
+	apply(s.Settings.Transient, settings.Transient)
+	writeJSON(w, map[string]interface{}{"acknowledged": true})
+}
+
+func (s *Server) votingConfigExclusions(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch r.Method {
+	case http.MethodDelete:
+		s.VotingConfigExclusions = nil
+	case http.MethodPost:
+		names := r.URL.Query().Get("node_names")
+		if len(parts) == 3 {
+			names = parts[2]
+		}
+		for _, name := range strings.Split(names, ",") {
+			if name != "" {
+				s.VotingConfigExclusions = append(s.VotingConfigExclusions, name)
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) nodes(w http.ResponseWriter) {
+	nodes := map[string]esclient.Node{}
+	for i, node := range s.Nodes {
+		id := node.ID
+		if id == "" {
+			id = fmt.Sprintf("node-%d", i)
+		}
+		nodes[id] = node
+	}
+	writeJSON(w, map[string]interface{}{"nodes": nodes})
+}
+
+func (s *Server) putRepository(w http.ResponseWriter, r *http.Request, name string) {
+	repository := esclient.SnapshotRepository{}
+	if err := json.NewDecoder(r.Body).Decode(&repository); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.Repositories[name] = repository
+	writeJSON(w, map[string]interface{}{"acknowledged": true})
+}
+
+func (s *Server) createSnapshot(w http.ResponseWriter, repository, name string) {
+	if _, ok := s.Repositories[repository]; !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("repository %v missing", repository))
+		return
+	}
+	if s.Snapshots[repository] == nil {
+		s.Snapshots[repository] = map[string]esclient.Snapshot{}
+	}
+	if _, ok := s.Snapshots[repository][name]; ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("snapshot %v already exists", name))
+		return
+	}
+
+	var indices []string
+	for _, index := range s.Indices {
+		indices = append(indices, index.Index)
+	}
+	sort.Strings(indices)
+	s.Snapshots[repository][name] = esclient.Snapshot{Snapshot: name, State: s.SnapshotState, Indices: indices}
+	writeJSON(w, map[string]interface{}{"accepted": true})
+}
+
+func (s *Server) getSnapshot(w http.ResponseWriter, repository, name string) {
+	snapshot, ok := s.Snapshots[repository][name]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("snapshot %v missing", name))
+		return
+	}
+	writeJSON(w, map[string]interface{}{"snapshots": []esclient.Snapshot{snapshot}})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  map[string]string{"reason": reason},
+		"status": status,
+	})
+}
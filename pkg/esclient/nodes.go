@@ -0,0 +1,51 @@
+package esclient
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+type Node struct {
+	ID      string   `json:"-"`
+	Name    string   `json:"name"`
+	Version string   `json:"version"`
+	Roles   []string `json:"roles"`
+}
+
+type nodesInfo struct {
+	Nodes map[string]Node `json:"nodes"`
+}
+
+// Nodes returns the nodes that have joined the cluster, ordered by name
+func (c *Client) Nodes() ([]Node, error) {
+	info := &nodesInfo{}
+	query := url.Values{"filter_path": {"nodes.*.name,nodes.*.version,nodes.*.roles"}}
+	if err := c.do(http.MethodGet, "/_nodes", query, nil, info); err != nil {
+		return nil, err
+	}
+
+	var nodes []Node
+	for id, node := range info.Nodes {
+		node.ID = id
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	return nodes, nil
+}
+
+// SyncedFlush flushes all indices, marking idle shards so they recover
+// quickly after a restart. Elasticsearch 7.6 deprecated synced flush in
+// favour of a regular flush. A conflict means some shards could not be synced
+// and is not treated as an error.
+func (c *Client) SyncedFlush(major, minor int) error {
+	path := "/_flush/synced"
+	if major > 7 || (major == 7 && minor >= 6) {
+		path = "/_flush"
+	}
+	err := c.do(http.MethodPost, path, nil, nil, nil)
+	if e, ok := err.(*Error); ok && e.StatusCode == http.StatusConflict {
+		return nil
+	}
+	return err
+}
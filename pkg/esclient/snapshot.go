@@ -0,0 +1,57 @@
+package esclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// SnapshotRepository registers where snapshots are stored, for example an
+// fs repository with a location setting
+type SnapshotRepository struct {
+	Type     string                 `json:"type"`
+	Settings map[string]interface{} `json:"settings,omitempty"`
+}
+
+const (
+	SnapshotInProgress = "IN_PROGRESS"
+	SnapshotSuccess    = "SUCCESS"
+	SnapshotPartial    = "PARTIAL"
+	SnapshotFailed     = "FAILED"
+)
+
+type Snapshot struct {
+	Snapshot string   `json:"snapshot"`
+	State    string   `json:"state"`
+	Indices  []string `json:"indices,omitempty"`
+}
+
+type snapshots struct {
+	Snapshots []Snapshot `json:"snapshots"`
+}
+
+// PutSnapshotRepository creates or updates a snapshot repository
+func (c *Client) PutSnapshotRepository(name string, repository SnapshotRepository) error {
+	return c.do(http.MethodPut, "/_snapshot/"+url.PathEscape(name), nil, repository, nil)
+}
+
+// CreateSnapshot starts a snapshot of every index without waiting for it to
+// complete, use GetSnapshot to follow its progress
+func (c *Client) CreateSnapshot(repository, name string) error {
+	path := fmt.Sprintf("/_snapshot/%v/%v", url.PathEscape(repository), url.PathEscape(name))
+	return c.do(http.MethodPut, path, url.Values{"wait_for_completion": {"false"}}, nil, nil)
+}
+
+// GetSnapshot returns a snapshot, or an error satisfying IsNotFound if it
+// does not exist
+func (c *Client) GetSnapshot(repository, name string) (*Snapshot, error) {
+	path := fmt.Sprintf("/_snapshot/%v/%v", url.PathEscape(repository), url.PathEscape(name))
+	result := &snapshots{}
+	if err := c.do(http.MethodGet, path, nil, nil, result); err != nil {
+		return nil, err
+	}
+	if len(result.Snapshots) == 0 {
+		return nil, &Error{StatusCode: http.StatusNotFound, Body: fmt.Sprintf("snapshot %v not found", name)}
+	}
+	return &result.Snapshots[0], nil
+}
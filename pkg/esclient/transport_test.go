@@ -0,0 +1,25 @@
+package esclient
+
+import "testing"
+
+func TestTransportShared(t *testing.T) {
+	first, err := New(Config{URL: "http://test-a:9200"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := New(Config{URL: "http://test-b:9200", Username: "elastic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	insecure, err := New(Config{URL: "https://test-a:9200", InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.httpClient.Transport != second.httpClient.Transport {
+		t.Errorf("expected clients with the same tls settings to share a transport")
+	}
+	if first.httpClient.Transport == insecure.httpClient.Transport {
+		t.Errorf("expected clients with different tls settings to use their own transport")
+	}
+}
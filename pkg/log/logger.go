@@ -1,41 +1,88 @@
 package log
 
 import (
+	"fmt"
+	"sync"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"sync"
 )
 
 var logger Logger
-var once sync.Once
+var mu sync.Mutex
 
 type Logger interface {
 	Debugf(string, ...interface{})
 	Infof(string, ...interface{})
 	Errorf(string, ...interface{})
 	Panicf(string, ...interface{})
+
+	// With returns a logger adding the given key value pairs to every line
+	With(keysAndValues ...interface{}) Logger
 }
 
-func NewLogger() Logger {
-	once.Do(func() {
+type zapLogger struct {
+	*zap.SugaredLogger
+}
 
-		encoderConfig := zap.NewDevelopmentEncoderConfig()
+func (l zapLogger) With(keysAndValues ...interface{}) Logger {
+	return zapLogger{l.SugaredLogger.With(keysAndValues...)}
+}
 
-		config := &zap.Config{
-			Level:            zap.NewAtomicLevelAt(zapcore.DebugLevel),
-			Encoding:         "console",
-			EncoderConfig:    encoderConfig,
-			OutputPaths:      []string{"stdout"},
-			ErrorOutputPaths: []string{"stdout"},
-		}
+// Configure replaces the logger returned by NewLogger. Level is one of debug,
+// info, warn or error and format is console or json.
+func Configure(level, format string) error {
+	var atomicLevel zap.AtomicLevel
+	if err := atomicLevel.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+
+	var encoderConfig zapcore.EncoderConfig
+	switch format {
+	case "console":
+		encoderConfig = zap.NewDevelopmentEncoderConfig()
+	case "json":
+		encoderConfig = zap.NewProductionEncoderConfig()
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	default:
+		return fmt.Errorf("invalid log format %q, must be console or json", format)
+	}
+
+	config := &zap.Config{
+		Level:            atomicLevel,
+		Encoding:         format,
+		EncoderConfig:    encoderConfig,
+		OutputPaths:      []string{"stdout"},
+		ErrorOutputPaths: []string{"stdout"},
+	}
+
+	l, err := config.Build()
+	if err != nil {
+		return fmt.Errorf("could not create logger: %v", err)
+	}
+
+	zap.RedirectStdLog(l)
+
+	mu.Lock()
+	defer mu.Unlock()
+	logger = zapLogger{l.Sugar()}
+	return nil
+}
+
+// NewLogger returns the logger set up by Configure, defaulting to debug
+// level console output
+func NewLogger() Logger {
+	mu.Lock()
+	configured := logger != nil
+	mu.Unlock()
 
-		if l, err := config.Build(); err != nil {
-			panic("Could not create logger")
-		} else {
-			zap.RedirectStdLog(l)
-			logger = l.Sugar()
+	if !configured {
+		if err := Configure("debug", "console"); err != nil {
+			panic(err)
 		}
-	})
+	}
 
+	mu.Lock()
+	defer mu.Unlock()
 	return logger
 }
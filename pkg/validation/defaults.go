@@ -0,0 +1,105 @@
+package validation
+
+import (
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// maxHeapSize is the largest heap the JVM addresses with compressed object
+// pointers
+var maxHeapSize = resource.MustParse("31Gi")
+
+// SetClusterDefaults fills in the fields of a cluster the controller would
+// otherwise default, so the stored spec shows what is run. Fields derived
+// from other fields, such as the replicas of data pools following
+// spec.size, are left unset, except for the heap of pools with a memory
+// limit.
+func SetClusterDefaults(cluster *esV1.Cluster) {
+	spec := &cluster.Spec
+	if spec.Version == "" {
+		spec.Version = esV1.DefaultVersion
+	}
+	if spec.DeletionPolicy == "" {
+		spec.DeletionPolicy = esV1.DeletionPolicyDelete
+	}
+	setStorageDefaults(&spec.Storage)
+	if spec.Connection != nil && spec.Connection.Scheme == "" {
+		spec.Connection.Scheme = "http"
+	}
+	if spec.HTTP != nil && spec.HTTP.ServiceType == "" {
+		spec.HTTP.ServiceType = corev1.ServiceTypeClusterIP
+	}
+
+	for i := range spec.NodePools {
+		pool := &spec.NodePools[i]
+		if pool.Storage != nil {
+			setStorageDefaults(pool.Storage)
+		}
+		setResourceDefaults(&pool.Resources)
+		if pool.Heap == nil {
+			pool.Heap = DefaultHeap(pool.Resources)
+		}
+	}
+}
+
+// SetClusterUpdateDefaults sets the defaults of an updated cluster. A heap
+// left at the default of the previous memory limit of its pool follows
+// changes to the limit.
+func SetClusterUpdateDefaults(cluster, old *esV1.Cluster) {
+	previous := map[string]*esV1.NodePool{}
+	for i := range old.Spec.NodePools {
+		previous[old.Spec.NodePools[i].Name] = &old.Spec.NodePools[i]
+	}
+
+	for i := range cluster.Spec.NodePools {
+		pool := &cluster.Spec.NodePools[i]
+		oldPool, ok := previous[pool.Name]
+		if !ok || pool.Heap == nil || oldPool.Heap == nil || pool.Heap.Cmp(*oldPool.Heap) != 0 {
+			continue
+		}
+		if heap := DefaultHeap(oldPool.Resources); heap != nil && heap.Cmp(*oldPool.Heap) == 0 {
+			pool.Heap = nil
+		}
+	}
+	SetClusterDefaults(cluster)
+}
+
+// DefaultHeap returns the JVM heap of nodes with resources, half their
+// memory limit capped at maxHeapSize. Nodes without a memory limit use the
+// default heap of the image, and nil is returned.
+func DefaultHeap(resources corev1.ResourceRequirements) *resource.Quantity {
+	limit, ok := resources.Limits[corev1.ResourceMemory]
+	if !ok {
+		return nil
+	}
+	heap := resource.NewQuantity(limit.Value()/2, resource.BinarySI)
+	if heap.Cmp(maxHeapSize) > 0 {
+		capped := maxHeapSize.DeepCopy()
+		return &capped
+	}
+	return heap
+}
+
+func setStorageDefaults(storage *esV1.StorageSpec) {
+	if storage.Size.IsZero() {
+		storage.Size = resource.MustParse(esV1.DefaultStorageSize)
+	}
+	if len(storage.AccessModes) == 0 {
+		storage.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+}
+
+// setResourceDefaults requests the resources a pool is limited to, as the
+// scheduler places pods by their requests
+func setResourceDefaults(resources *corev1.ResourceRequirements) {
+	for name, limit := range resources.Limits {
+		if _, ok := resources.Requests[name]; ok {
+			continue
+		}
+		if resources.Requests == nil {
+			resources.Requests = corev1.ResourceList{}
+		}
+		resources.Requests[name] = limit.DeepCopy()
+	}
+}
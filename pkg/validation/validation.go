@@ -0,0 +1,322 @@
+// Package validation implements the rules and defaults of Cluster resources.
+// The controller refuses to reconcile a spec failing ValidateClusterSpec,
+// while the admission webhook additionally enforces the rules of
+// ValidateCluster and ValidateClusterUpdate when clusters are written.
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// reservedPoolNames would give pools services clashing with the services of
+// the cluster
+var reservedPoolNames = map[string]bool{
+	"http":           true,
+	"master-service": true,
+}
+
+var specPath = field.NewPath("spec")
+
+// managedSettings are the elasticsearch.yml settings set by the operator,
+// which spec.config cannot override. node.name is set to the pod name, which
+// the operator relies on to match nodes to pods.
+var managedSettings = map[string]bool{
+	"cluster.name":                       true,
+	"network.host":                       true,
+	"node.name":                          true,
+	"node.master":                        true,
+	"node.data":                          true,
+	"node.ingest":                        true,
+	"node.ml":                            true,
+	"path.data":                          true,
+	"http.port":                          true,
+	"transport.port":                     true,
+	"transport.tcp.port":                 true,
+	"cluster.initial_master_nodes":       true,
+	"discovery.seed_hosts":               true,
+	"discovery.zen.ping.unicast.hosts":   true,
+	"discovery.zen.minimum_master_nodes": true,
+}
+
+// IsManagedSetting returns whether the operator sets the elasticsearch.yml
+// setting key
+func IsManagedSetting(key string) bool {
+	return managedSettings[key]
+}
+
+// ValidateClusterSpec checks the spec of a cluster can be turned into
+// workloads
+func ValidateClusterSpec(spec *esV1.ClusterSpec) field.ErrorList {
+	var errs field.ErrorList
+	if spec.Size < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("size"), spec.Size, "must not be negative"))
+	}
+	errs = append(errs, validateConfig(spec.Config, specPath.Child("config"))...)
+	errs = append(errs, validateNodePools(spec)...)
+	errs = append(errs, validateHTTP(spec.HTTP, specPath.Child("http"))...)
+	errs = append(errs, validateDeletion(spec)...)
+	return errs
+}
+
+func validateNodePools(spec *esV1.ClusterSpec) field.ErrorList {
+	var errs field.ErrorList
+	path := specPath.Child("nodePools")
+	names := map[string]bool{}
+	for i := range spec.NodePools {
+		pool := &spec.NodePools[i]
+		poolPath := path.Index(i)
+
+		for _, msg := range validation.IsDNS1123Label(pool.Name) {
+			errs = append(errs, field.Invalid(poolPath.Child("name"), pool.Name, msg))
+		}
+		if names[pool.Name] {
+			errs = append(errs, field.Duplicate(poolPath.Child("name"), pool.Name))
+		}
+		if reservedPoolNames[pool.Name] {
+			errs = append(errs, field.Invalid(poolPath.Child("name"), pool.Name, "is reserved"))
+		}
+		names[pool.Name] = true
+
+		rolesPath := poolPath.Child("roles")
+		if len(pool.Roles) == 0 {
+			errs = append(errs, field.Required(rolesPath, "node pool has no roles"))
+		}
+		for j, role := range pool.Roles {
+			switch role {
+			case esV1.NodeRoleMaster, esV1.NodeRoleData, esV1.NodeRoleIngest, esV1.NodeRoleML:
+			case esV1.NodeRoleCoordinating:
+				if len(pool.Roles) > 1 {
+					errs = append(errs, field.Invalid(rolesPath.Index(j), role, "cannot be combined with other roles"))
+				}
+			default:
+				errs = append(errs, field.NotSupported(rolesPath.Index(j), role, []string{
+					string(esV1.NodeRoleMaster), string(esV1.NodeRoleData), string(esV1.NodeRoleIngest),
+					string(esV1.NodeRoleCoordinating), string(esV1.NodeRoleML),
+				}))
+			}
+		}
+
+		if pool.Replicas != nil && *pool.Replicas < 0 {
+			errs = append(errs, field.Invalid(poolPath.Child("replicas"), *pool.Replicas, "must not be negative"))
+		}
+		errs = append(errs, validateHeap(pool, poolPath.Child("heap"))...)
+		errs = append(errs, validateConfig(pool.Config, poolPath.Child("config"))...)
+		if pool.PodTemplate != nil {
+			errs = append(errs, validatePodTemplate(pool.PodTemplate, poolPath.Child("podTemplate"))...)
+		}
+	}
+
+	if MasterNodes(spec.DefaultedNodePools()) == 0 {
+		errs = append(errs, field.Invalid(path, len(spec.NodePools), "cluster has no master eligible nodes"))
+	}
+	return errs
+}
+
+// validateHeap requires the heap of a pool to fit in the memory limit of its
+// nodes, as the JVM would be killed once the heap grows past it
+func validateHeap(pool *esV1.NodePool, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if pool.Heap == nil {
+		return errs
+	}
+	if pool.Heap.Sign() <= 0 {
+		errs = append(errs, field.Invalid(path, pool.Heap.String(), "must be positive"))
+		return errs
+	}
+	if limit, ok := pool.Resources.Limits[corev1.ResourceMemory]; ok && pool.Heap.Cmp(limit) > 0 {
+		errs = append(errs, field.Invalid(path, pool.Heap.String(),
+			fmt.Sprintf("must not exceed the memory limit of %v", limit.String())))
+	}
+	return errs
+}
+
+// validatePodTemplate requires the containers of a pod template to be named,
+// as they are merged with the containers of the operator by name
+func validatePodTemplate(template *corev1.PodTemplateSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	specPath := path.Child("spec")
+	for i, container := range template.Spec.InitContainers {
+		if container.Name == "" {
+			errs = append(errs, field.Required(specPath.Child("initContainers").Index(i).Child("name"), ""))
+		}
+	}
+	for i, container := range template.Spec.Containers {
+		if container.Name == "" {
+			errs = append(errs, field.Required(specPath.Child("containers").Index(i).Child("name"), ""))
+		}
+	}
+	return errs
+}
+
+func validateConfig(config map[string]string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		switch {
+		case strings.TrimSpace(key) == "":
+			errs = append(errs, field.Invalid(path, key, "setting name must not be empty"))
+		case IsManagedSetting(key):
+			errs = append(errs, field.Forbidden(path.Key(key), "is managed by the operator"))
+		}
+	}
+	return errs
+}
+
+func validateHTTP(http *esV1.HTTPSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if http == nil {
+		return errs
+	}
+
+	switch http.ServiceType {
+	case "", corev1.ServiceTypeClusterIP, corev1.ServiceTypeNodePort, corev1.ServiceTypeLoadBalancer:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("serviceType"), http.ServiceType, []string{
+			string(corev1.ServiceTypeClusterIP), string(corev1.ServiceTypeNodePort), string(corev1.ServiceTypeLoadBalancer),
+		}))
+	}
+
+	if len(http.LoadBalancerSourceRanges) > 0 && http.ServiceType != corev1.ServiceTypeLoadBalancer {
+		errs = append(errs, field.Forbidden(path.Child("loadBalancerSourceRanges"), "requires a LoadBalancer service"))
+	}
+	return errs
+}
+
+func validateDeletion(spec *esV1.ClusterSpec) field.ErrorList {
+	var errs field.ErrorList
+	switch spec.DeletionPolicy {
+	case "", esV1.DeletionPolicyDelete, esV1.DeletionPolicyRetain:
+	case esV1.DeletionPolicySnapshot:
+		if spec.Snapshot == nil || spec.Snapshot.Repository == "" {
+			errs = append(errs, field.Required(specPath.Child("snapshot", "repository"),
+				fmt.Sprintf("deletion policy %s requires a snapshot repository", esV1.DeletionPolicySnapshot)))
+		}
+	default:
+		errs = append(errs, field.NotSupported(specPath.Child("deletionPolicy"), spec.DeletionPolicy, []string{
+			string(esV1.DeletionPolicyDelete), string(esV1.DeletionPolicyRetain), string(esV1.DeletionPolicySnapshot),
+		}))
+	}
+	return errs
+}
+
+// ValidateCluster checks a new cluster
+func ValidateCluster(cluster *esV1.Cluster) field.ErrorList {
+	errs := ValidateClusterSpec(&cluster.Spec)
+	errs = append(errs, validateVersion(cluster.Spec.Version)...)
+	errs = append(errs, validateMasterQuorum(&cluster.Spec)...)
+	return errs
+}
+
+// ValidateClusterUpdate checks the changes made to an existing cluster.
+// Updates leaving the spec untouched, such as the finalizer being added or
+// removed, are always allowed so a cluster admitted under older rules can
+// still be managed.
+func ValidateClusterUpdate(cluster, old *esV1.Cluster) field.ErrorList {
+	if apiequality.Semantic.DeepEqual(cluster.Spec, old.Spec) {
+		return nil
+	}
+
+	errs := ValidateClusterSpec(&cluster.Spec)
+	errs = append(errs, validateVersion(cluster.Spec.Version)...)
+	if MasterNodes(cluster.Spec.DefaultedNodePools()) != MasterNodes(old.Spec.DefaultedNodePools()) {
+		errs = append(errs, validateMasterQuorum(&cluster.Spec)...)
+	}
+
+	if cluster.Spec.Name != old.Spec.Name {
+		errs = append(errs, field.Forbidden(specPath.Child("name"), "is immutable"))
+	}
+	errs = append(errs, validateVersionUpdate(cluster.Spec.Version, old.Spec.Version)...)
+	errs = append(errs, validateStorageUpdate(&cluster.Spec, &old.Spec)...)
+	return errs
+}
+
+// validateMasterQuorum requires an odd number of master eligible nodes, as
+// an even number tolerates no more failures than one node less and risks
+// splitting the cluster in two halves
+func validateMasterQuorum(spec *esV1.ClusterSpec) field.ErrorList {
+	var errs field.ErrorList
+	if masters := MasterNodes(spec.DefaultedNodePools()); masters > 0 && masters%2 == 0 {
+		errs = append(errs, field.Invalid(specPath.Child("nodePools"), masters,
+			"the number of master eligible nodes must be odd"))
+	}
+	return errs
+}
+
+func validateVersion(version string) field.ErrorList {
+	var errs field.ErrorList
+	if _, err := ParseVersion(version); err != nil {
+		errs = append(errs, field.Invalid(specPath.Child("version"), version, err.Error()))
+	}
+	return errs
+}
+
+// validateVersionUpdate forbids downgrades, as nodes cannot read the data
+// written by a later version
+func validateVersionUpdate(version, old string) field.ErrorList {
+	var errs field.ErrorList
+	v, err := ParseVersion(version)
+	if err != nil {
+		return errs
+	}
+	// an invalid old version never ran, so any version may replace it
+	previous, err := ParseVersion(old)
+	if err != nil {
+		return errs
+	}
+	if v.LessThan(previous) {
+		errs = append(errs, field.Forbidden(specPath.Child("version"),
+			fmt.Sprintf("cannot downgrade from %v to %v", previous, v)))
+	}
+	return errs
+}
+
+// validateStorageUpdate forbids shrinking the storage of a pool, as the
+// persistent volume claims of its nodes cannot be shrunk
+func validateStorageUpdate(spec, old *esV1.ClusterSpec) field.ErrorList {
+	var errs field.ErrorList
+	previous := map[string]resource.Quantity{}
+	for _, pool := range old.DefaultedNodePools() {
+		if pool.Storage != nil {
+			previous[pool.Name] = storageSize(pool.Storage)
+		}
+	}
+
+	for i, pool := range spec.DefaultedNodePools() {
+		old, ok := previous[pool.Name]
+		if pool.Storage == nil || !ok {
+			continue
+		}
+		size := storageSize(pool.Storage)
+		if size.Cmp(old) >= 0 {
+			continue
+		}
+
+		path := specPath.Child("storage", "size")
+		if len(spec.NodePools) > 0 && spec.NodePools[i].Storage != nil {
+			path = specPath.Child("nodePools").Index(i).Child("storage", "size")
+		}
+		errs = append(errs, field.Forbidden(path,
+			fmt.Sprintf("storage of node pool %q cannot shrink from %v to %v", pool.Name, old.String(), size.String())))
+	}
+	return errs
+}
+
+func storageSize(storage *esV1.StorageSpec) resource.Quantity {
+	if storage.Size.IsZero() {
+		return resource.MustParse(esV1.DefaultStorageSize)
+	}
+	return storage.Size
+}
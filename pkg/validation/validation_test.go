@@ -0,0 +1,251 @@
+package validation
+
+import (
+	"testing"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func newCluster() *esV1.Cluster {
+	cluster := &esV1.Cluster{}
+	cluster.Name = "test"
+	cluster.Spec = esV1.ClusterSpec{
+		Name:    "test",
+		Version: "6.4.2",
+		Size:    2,
+		Storage: esV1.StorageSpec{Size: resource.MustParse("10Gi")},
+	}
+	return cluster
+}
+
+func withPools(cluster *esV1.Cluster, pools ...esV1.NodePool) *esV1.Cluster {
+	cluster.Spec.NodePools = pools
+	return cluster
+}
+
+func withMemory(pool esV1.NodePool, limit, heap string) esV1.NodePool {
+	pool.Resources.Limits = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(limit)}
+	quantity := resource.MustParse(heap)
+	pool.Heap = &quantity
+	return pool
+}
+
+func withPodTemplate(pool esV1.NodePool, container string) esV1.NodePool {
+	pool.PodTemplate = &corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: container, Image: "busybox"}}},
+	}
+	return pool
+}
+
+func TestValidateCluster(t *testing.T) {
+	tests := []struct {
+		name    string
+		cluster *esV1.Cluster
+		valid   bool
+	}{
+		{"default pools", newCluster(), true},
+		{"three masters", withPools(newCluster(),
+			esV1.NodePool{Name: "master", Roles: []esV1.NodeRole{esV1.NodeRoleMaster}, Replicas: int32Ptr(3)},
+			esV1.NodePool{Name: "data", Roles: []esV1.NodeRole{esV1.NodeRoleData}},
+		), true},
+		{"two masters", withPools(newCluster(),
+			esV1.NodePool{Name: "master", Roles: []esV1.NodeRole{esV1.NodeRoleMaster}, Replicas: int32Ptr(2)},
+		), false},
+		{"no masters", withPools(newCluster(),
+			esV1.NodePool{Name: "data", Roles: []esV1.NodeRole{esV1.NodeRoleData}},
+		), false},
+		{"reserved pool name", withPools(newCluster(),
+			esV1.NodePool{Name: "http", Roles: []esV1.NodeRole{esV1.NodeRoleMaster}},
+		), false},
+		{"coordinating with other roles", withPools(newCluster(),
+			esV1.NodePool{Name: "master", Roles: []esV1.NodeRole{esV1.NodeRoleMaster, esV1.NodeRoleCoordinating}},
+		), false},
+		{"invalid version", func() *esV1.Cluster {
+			cluster := newCluster()
+			cluster.Spec.Version = "6.4"
+			return cluster
+		}(), false},
+		{"config", func() *esV1.Cluster {
+			cluster := newCluster()
+			cluster.Spec.Config = map[string]string{"indices.memory.index_buffer_size": "20%"}
+			return cluster
+		}(), true},
+		{"managed setting in config", func() *esV1.Cluster {
+			cluster := newCluster()
+			cluster.Spec.Config = map[string]string{"discovery.seed_hosts": "other"}
+			return cluster
+		}(), false},
+		{"node name in config", func() *esV1.Cluster {
+			cluster := newCluster()
+			cluster.Spec.Config = map[string]string{"node.name": "node"}
+			return cluster
+		}(), false},
+		{"zen discovery tuning in config", func() *esV1.Cluster {
+			cluster := newCluster()
+			cluster.Spec.Config = map[string]string{"discovery.zen.ping_timeout": "10s"}
+			return cluster
+		}(), true},
+		{"managed setting in pool config", withPools(newCluster(),
+			esV1.NodePool{Name: "master", Roles: []esV1.NodeRole{esV1.NodeRoleMaster}, Config: map[string]string{"node.data": "true"}},
+		), false},
+		{"heap within memory limit", withPools(newCluster(),
+			withMemory(esV1.NodePool{Name: "master", Roles: []esV1.NodeRole{esV1.NodeRoleMaster}}, "4Gi", "3Gi"),
+		), true},
+		{"heap above memory limit", withPools(newCluster(),
+			withMemory(esV1.NodePool{Name: "master", Roles: []esV1.NodeRole{esV1.NodeRoleMaster}}, "4Gi", "5Gi"),
+		), false},
+		{"pod template", withPools(newCluster(),
+			withPodTemplate(esV1.NodePool{Name: "master", Roles: []esV1.NodeRole{esV1.NodeRoleMaster}}, "log-shipper"),
+		), true},
+		{"pod template with unnamed container", withPools(newCluster(),
+			withPodTemplate(esV1.NodePool{Name: "master", Roles: []esV1.NodeRole{esV1.NodeRoleMaster}}, ""),
+		), false},
+		{"snapshot policy without repository", func() *esV1.Cluster {
+			cluster := newCluster()
+			cluster.Spec.DeletionPolicy = esV1.DeletionPolicySnapshot
+			return cluster
+		}(), false},
+	}
+
+	for _, test := range tests {
+		errs := ValidateCluster(test.cluster)
+		if valid := len(errs) == 0; valid != test.valid {
+			t.Errorf("%s: expected valid %v, got errors %v", test.name, test.valid, errs)
+		}
+	}
+}
+
+func TestValidateClusterUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(cluster *esV1.Cluster)
+		valid  bool
+	}{
+		{"upgrade", func(cluster *esV1.Cluster) { cluster.Spec.Version = "6.5.0" }, true},
+		{"downgrade", func(cluster *esV1.Cluster) { cluster.Spec.Version = "6.4.1" }, false},
+		{"rename", func(cluster *esV1.Cluster) { cluster.Spec.Name = "other" }, false},
+		{"grow storage", func(cluster *esV1.Cluster) { cluster.Spec.Storage.Size = resource.MustParse("20Gi") }, true},
+		{"shrink storage", func(cluster *esV1.Cluster) { cluster.Spec.Storage.Size = resource.MustParse("5Gi") }, false},
+		{"scale data nodes", func(cluster *esV1.Cluster) { cluster.Spec.Size = 5 }, true},
+		{"even masters", func(cluster *esV1.Cluster) {
+			withPools(cluster,
+				esV1.NodePool{Name: "master", Roles: []esV1.NodeRole{esV1.NodeRoleMaster}, Replicas: int32Ptr(2)},
+			)
+		}, false},
+	}
+
+	for _, test := range tests {
+		old := newCluster()
+		cluster := old.DeepCopy()
+		test.update(cluster)
+		errs := ValidateClusterUpdate(cluster, old)
+		if valid := len(errs) == 0; valid != test.valid {
+			t.Errorf("%s: expected valid %v, got errors %v", test.name, test.valid, errs)
+		}
+	}
+}
+
+func TestValidateClusterUpdateUnchangedSpec(t *testing.T) {
+	// admitted before masters had to be odd
+	old := withPools(newCluster(),
+		esV1.NodePool{Name: "master", Roles: []esV1.NodeRole{esV1.NodeRoleMaster}, Replicas: int32Ptr(2)},
+	)
+	cluster := old.DeepCopy()
+	cluster.Finalizers = []string{"cleanup"}
+	if errs := ValidateClusterUpdate(cluster, old); len(errs) > 0 {
+		t.Errorf("expected metadata update to be allowed, got %v", errs)
+	}
+
+	cluster.Spec.Size = 3
+	if errs := ValidateClusterUpdate(cluster, old); len(errs) > 0 {
+		t.Errorf("expected update keeping the master count to be allowed, got %v", errs)
+	}
+}
+
+func TestSetClusterDefaults(t *testing.T) {
+	cluster := withPools(&esV1.Cluster{}, esV1.NodePool{
+		Name:    "data",
+		Roles:   []esV1.NodeRole{esV1.NodeRoleData},
+		Storage: &esV1.StorageSpec{},
+		Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")},
+		},
+	})
+	cluster.Spec.HTTP = &esV1.HTTPSpec{}
+	SetClusterDefaults(cluster)
+
+	spec := cluster.Spec
+	if spec.Version != esV1.DefaultVersion {
+		t.Errorf("expected version %v, got %v", esV1.DefaultVersion, spec.Version)
+	}
+	if spec.DeletionPolicy != esV1.DeletionPolicyDelete {
+		t.Errorf("expected deletion policy %v, got %v", esV1.DeletionPolicyDelete, spec.DeletionPolicy)
+	}
+	if spec.HTTP.ServiceType != corev1.ServiceTypeClusterIP {
+		t.Errorf("expected service type %v, got %v", corev1.ServiceTypeClusterIP, spec.HTTP.ServiceType)
+	}
+	if size := spec.NodePools[0].Storage.Size; size.String() != esV1.DefaultStorageSize {
+		t.Errorf("expected pool storage %v, got %v", esV1.DefaultStorageSize, size.String())
+	}
+	if spec.NodePools[0].Replicas != nil {
+		t.Errorf("expected pool replicas to follow spec.size, got %v", *spec.NodePools[0].Replicas)
+	}
+	memory := spec.NodePools[0].Resources.Requests[corev1.ResourceMemory]
+	if memory.String() != "4Gi" {
+		t.Errorf("expected memory request of the limit, got %v", memory.String())
+	}
+	if heap := spec.NodePools[0].Heap; heap == nil || heap.String() != "2Gi" {
+		t.Errorf("expected heap of half the memory limit, got %v", heap)
+	}
+}
+
+func TestDefaultHeap(t *testing.T) {
+	tests := []struct {
+		limit    string
+		expected string
+	}{
+		{"", ""},
+		{"4Gi", "2Gi"},
+		{"128Gi", "31Gi"},
+	}
+
+	for _, test := range tests {
+		resources := corev1.ResourceRequirements{}
+		if test.limit != "" {
+			resources.Limits = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(test.limit)}
+		}
+		heap := DefaultHeap(resources)
+		if heap == nil && test.expected != "" || heap != nil && heap.String() != test.expected {
+			t.Errorf("limit %q: expected heap %q, got %v", test.limit, test.expected, heap)
+		}
+	}
+}
+
+func TestSetClusterUpdateDefaultsHeap(t *testing.T) {
+	tests := []struct {
+		name     string
+		heap     string
+		expected string
+	}{
+		{"defaulted heap follows the limit", "2Gi", "4Gi"},
+		{"overridden heap kept", "1Gi", "1Gi"},
+	}
+
+	for _, test := range tests {
+		old := withPools(newCluster(),
+			withMemory(esV1.NodePool{Name: "master", Roles: []esV1.NodeRole{esV1.NodeRoleMaster}}, "4Gi", test.heap))
+		cluster := old.DeepCopy()
+		cluster.Spec.NodePools[0].Resources.Limits[corev1.ResourceMemory] = resource.MustParse("8Gi")
+
+		SetClusterUpdateDefaults(cluster, old)
+		if heap := cluster.Spec.NodePools[0].Heap; heap.String() != test.expected {
+			t.Errorf("%s: expected heap %s, got %v", test.name, test.expected, heap)
+		}
+	}
+}
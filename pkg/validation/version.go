@@ -0,0 +1,67 @@
+package validation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+)
+
+// Version is an elasticsearch version
+type Version struct {
+	Major, Minor, Patch int
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// LessThan returns whether v is older than other
+func (v Version) LessThan(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+// ParseVersion parses an elasticsearch version of the form
+// major.minor.patch, an empty version being DefaultVersion
+func ParseVersion(version string) (Version, error) {
+	var numbers [3]int
+	parts := strings.Split(defaultVersion(version), ".")
+	if len(parts) != len(numbers) {
+		return Version{}, fmt.Errorf("expected major.minor.patch")
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("expected major.minor.patch")
+		}
+		numbers[i] = n
+	}
+	return Version{numbers[0], numbers[1], numbers[2]}, nil
+}
+
+func defaultVersion(version string) string {
+	if version == "" {
+		return esV1.DefaultVersion
+	}
+	return version
+}
+
+// MasterNodes returns the number of master eligible nodes of defaulted node
+// pools. Pools with a negative number of replicas, which fail validation,
+// count as having none.
+func MasterNodes(pools []esV1.NodePool) int32 {
+	var count int32
+	for i := range pools {
+		if pools[i].HasRole(esV1.NodeRoleMaster) && *pools[i].Replicas > 0 {
+			count += *pools[i].Replicas
+		}
+	}
+	return count
+}
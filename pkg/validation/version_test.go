@@ -0,0 +1,43 @@
+package validation
+
+import (
+	"testing"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected Version
+		valid    bool
+	}{
+		{"6.4.2", Version{6, 4, 2}, true},
+		{"", Version{6, 1, 1}, true},
+		{"7.0", Version{}, false},
+		{"7.0.-1", Version{}, false},
+		{"7.x.0", Version{}, false},
+	}
+
+	for _, test := range tests {
+		v, err := ParseVersion(test.version)
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("%q: expected valid %v, got %v", test.version, test.valid, err)
+			continue
+		}
+		if v != test.expected {
+			t.Errorf("%q: expected %v, got %v", test.version, test.expected, v)
+		}
+	}
+}
+
+func TestMasterNodes(t *testing.T) {
+	pools := []esV1.NodePool{
+		{Name: "master", Roles: []esV1.NodeRole{esV1.NodeRoleMaster}, Replicas: int32Ptr(3)},
+		{Name: "data", Roles: []esV1.NodeRole{esV1.NodeRoleData}, Replicas: int32Ptr(5)},
+		{Name: "invalid", Roles: []esV1.NodeRole{esV1.NodeRoleMaster}, Replicas: int32Ptr(-1)},
+	}
+	if masters := MasterNodes(pools); masters != 3 {
+		t.Errorf("expected 3 master nodes, got %d", masters)
+	}
+}
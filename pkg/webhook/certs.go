@@ -0,0 +1,258 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/cert"
+)
+
+const (
+	caCertKey = "ca.crt"
+
+	// renewBefore is how long before expiring the serving certificate is
+	// replaced
+	renewBefore = 30 * 24 * time.Hour
+
+	// rotationInterval is how often the certificate is checked for renewal,
+	// and for a renewal by another replica
+	rotationInterval = time.Hour
+)
+
+// Certificate is the serving certificate of the webhooks and the authority
+// the api server trusts to have signed it
+type Certificate struct {
+	CACert []byte
+	Cert   []byte
+	Key    []byte
+}
+
+// Rotator serves the certificate kept in the webhook secret, renewing it
+// before it expires and picking up renewals by other replicas
+type Rotator struct {
+	clientset  kubernetes.Interface
+	namespace  string
+	secretName string
+	service    string
+
+	// pair holds the *tls.Certificate being served
+	pair atomic.Value
+	// caBundle is the bundle of authorities last registered with the api
+	// server, see Run
+	caBundle []byte
+	caCert   []byte
+}
+
+// NewRotator returns a rotator serving the certificate of the webhook
+// service, generating it if needed
+func NewRotator(clientset kubernetes.Interface, namespace, secretName, service string) (*Rotator, error) {
+	r := &Rotator{
+		clientset:  clientset,
+		namespace:  namespace,
+		secretName: secretName,
+		service:    service,
+	}
+	certificate, err := EnsureCertificate(clientset, namespace, secretName, service)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.serve(certificate); err != nil {
+		return nil, err
+	}
+	r.caCert = certificate.CACert
+	r.caBundle = certificate.CACert
+	return r, nil
+}
+
+// TLSConfig returns a configuration serving the latest certificate
+func (r *Rotator) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.pair.Load().(*tls.Certificate), nil
+		},
+	}
+}
+
+// CABundle returns the authorities the api server should trust
+func (r *Rotator) CABundle() []byte {
+	return r.caBundle
+}
+
+// Run checks the certificate every rotationInterval until stop is closed.
+// When its authority changes, register is called with a bundle of the new
+// and previous authorities before the new certificate is served, so the
+// api server trusts both this and replicas yet to pick up the change.
+func (r *Rotator) Run(stop <-chan struct{}, register func(caBundle []byte) error) {
+	logger := log.NewLogger()
+	wait.Until(func() {
+		if err := r.rotate(register); err != nil {
+			logger.Errorf("Failed to rotate webhook certificate: %v", err)
+		}
+	}, rotationInterval, stop)
+}
+
+func (r *Rotator) rotate(register func(caBundle []byte) error) error {
+	certificate, err := EnsureCertificate(r.clientset, r.namespace, r.secretName, r.service)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(certificate.CACert, r.caCert) {
+		caBundle := append(append([]byte{}, certificate.CACert...), r.caCert...)
+		if err := register(caBundle); err != nil {
+			return err
+		}
+		r.caBundle = caBundle
+		r.caCert = certificate.CACert
+	}
+	return r.serve(certificate)
+}
+
+func (r *Rotator) serve(certificate *Certificate) error {
+	pair, err := tls.X509KeyPair(certificate.Cert, certificate.Key)
+	if err != nil {
+		return fmt.Errorf("invalid webhook certificate: %v", err)
+	}
+	r.pair.Store(&pair)
+	return nil
+}
+
+// serviceDNSName is the name the api server verifies the certificate of a
+// webhook service against
+func serviceDNSName(namespace, service string) string {
+	return fmt.Sprintf("%s.%s.svc", service, namespace)
+}
+
+// EnsureCertificate returns the certificate of the webhook service kept in a
+// secret of its namespace. A self-signed certificate is generated when the
+// secret is missing, was issued for another service or is about to expire.
+// Replicas of the operator share the secret, the first one to write it wins.
+func EnsureCertificate(clientset kubernetes.Interface, namespace, secretName, service string) (*Certificate, error) {
+	secrets := clientset.CoreV1().Secrets(namespace)
+	dnsName := serviceDNSName(namespace, service)
+
+	secret, err := secrets.Get(secretName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		secret = nil
+	case err != nil:
+		return nil, err
+	default:
+		if certificate := certificateFromSecret(secret); certificate.valid(dnsName) {
+			return certificate, nil
+		}
+	}
+
+	certificate, err := newCertificate(namespace, service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate webhook certificate: %v", err)
+	}
+
+	if secret == nil {
+		_, err = secrets.Create(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: namespace},
+			Type:       corev1.SecretTypeTLS,
+			Data:       certificate.secretData(),
+		})
+	} else {
+		secret = secret.DeepCopy()
+		secret.Data = certificate.secretData()
+		_, err = secrets.Update(secret)
+	}
+
+	// another replica wrote the secret first
+	if apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err) {
+		if secret, err = secrets.Get(secretName, metav1.GetOptions{}); err != nil {
+			return nil, err
+		}
+		return certificateFromSecret(secret), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return certificate, nil
+}
+
+func certificateFromSecret(secret *corev1.Secret) *Certificate {
+	return &Certificate{
+		CACert: secret.Data[caCertKey],
+		Cert:   secret.Data[corev1.TLSCertKey],
+		Key:    secret.Data[corev1.TLSPrivateKeyKey],
+	}
+}
+
+func (c *Certificate) secretData() map[string][]byte {
+	return map[string][]byte{
+		caCertKey:               c.CACert,
+		corev1.TLSCertKey:       c.Cert,
+		corev1.TLSPrivateKeyKey: c.Key,
+	}
+}
+
+// valid returns whether the certificate is signed by its authority for
+// dnsName and does not expire soon
+func (c *Certificate) valid(dnsName string) bool {
+	if _, err := tls.X509KeyPair(c.Cert, c.Key); err != nil {
+		return false
+	}
+	certs, err := cert.ParseCertsPEM(c.Cert)
+	if err != nil {
+		return false
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(c.CACert) {
+		return false
+	}
+	_, err = certs[0].Verify(x509.VerifyOptions{
+		DNSName:     dnsName,
+		Roots:       roots,
+		CurrentTime: time.Now().Add(renewBefore),
+	})
+	return err == nil
+}
+
+// newCertificate generates an authority and the certificate it signs for
+// the webhook service
+func newCertificate(namespace, service string) (*Certificate, error) {
+	caKey, err := cert.NewPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := cert.NewSelfSignedCACert(cert.Config{CommonName: "elasticsearch-operator-webhook-ca"}, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := cert.NewPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	dnsName := serviceDNSName(namespace, service)
+	servingCert, err := cert.NewSignedCert(cert.Config{
+		CommonName: dnsName,
+		AltNames: cert.AltNames{
+			DNSNames: []string{service, fmt.Sprintf("%s.%s", service, namespace), dnsName, dnsName + ".cluster.local"},
+		},
+		Usages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, key, caCert, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Certificate{
+		CACert: cert.EncodeCertPEM(caCert),
+		Cert:   cert.EncodeCertPEM(servingCert),
+		Key:    cert.EncodePrivateKeyPEM(key),
+	}, nil
+}
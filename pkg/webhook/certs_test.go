@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"bytes"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEnsureCertificateReusesSecret(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	first, err := EnsureCertificate(clientset, "operator", "webhook", "elasticsearch-operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.valid(serviceDNSName("operator", "elasticsearch-operator")) {
+		t.Fatalf("expected a valid certificate for the service")
+	}
+
+	second, err := EnsureCertificate(clientset, "operator", "webhook", "elasticsearch-operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Cert, second.Cert) {
+		t.Errorf("expected the certificate in the secret reused")
+	}
+
+	// issued for another service
+	third, err := EnsureCertificate(clientset, "operator", "webhook", "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first.Cert, third.Cert) {
+		t.Errorf("expected a new certificate for another service")
+	}
+}
+
+func TestRotatorPicksUpRenewal(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	rotator, err := NewRotator(clientset, "operator", "webhook", "elasticsearch-operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	initial := rotator.CABundle()
+	served, err := rotator.TLSConfig().GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registered := [][]byte{}
+	register := func(caBundle []byte) error {
+		registered = append(registered, caBundle)
+		return nil
+	}
+	if err := rotator.rotate(register); err != nil {
+		t.Fatal(err)
+	}
+	if len(registered) != 0 {
+		t.Fatalf("expected nothing registered while the certificate is unchanged")
+	}
+
+	// another replica renews the certificate
+	renewed, err := newCertificate("operator", "elasticsearch-operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "operator"},
+		Data:       renewed.secretData(),
+	}
+	if _, err := clientset.CoreV1().Secrets("operator").Update(secret); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rotator.rotate(register); err != nil {
+		t.Fatal(err)
+	}
+	if len(registered) != 1 || !bytes.Contains(registered[0], renewed.CACert) || !bytes.Contains(registered[0], initial) {
+		t.Fatalf("expected the new and previous authorities registered, got %d registrations", len(registered))
+	}
+	rotated, err := rotator.TLSConfig().GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(rotated.Certificate[0], served.Certificate[0]) {
+		t.Errorf("expected the renewed certificate served")
+	}
+}
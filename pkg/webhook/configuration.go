@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/matt-tyler/elasticsearch-operator/pkg/apis/es"
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	esV2 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v2"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Config describes how the api server reaches the webhooks
+type Config struct {
+	// Name of the webhook configurations
+	Name string
+	// Namespace and Service name the service in front of the operator
+	Namespace string
+	Service   string
+	// CABundle is the authority the serving certificate is signed by
+	CABundle []byte
+}
+
+func (c Config) clientConfig(path string) admissionregistrationv1beta1.WebhookClientConfig {
+	return admissionregistrationv1beta1.WebhookClientConfig{
+		Service: &admissionregistrationv1beta1.ServiceReference{
+			Namespace: c.Namespace,
+			Name:      c.Service,
+			Path:      &path,
+		},
+		CABundle: c.CABundle,
+	}
+}
+
+// webhook returns a webhook intercepting creates and updates of clusters.
+// Requests are rejected while the operator is unavailable, rather than
+// admitting clusters it has not checked.
+func (c Config) webhook(name, path string) admissionregistrationv1beta1.Webhook {
+	failurePolicy := admissionregistrationv1beta1.Fail
+	return admissionregistrationv1beta1.Webhook{
+		Name:         name,
+		ClientConfig: c.clientConfig(path),
+		Rules: []admissionregistrationv1beta1.RuleWithOperations{{
+			Operations: []admissionregistrationv1beta1.OperationType{
+				admissionregistrationv1beta1.Create,
+				admissionregistrationv1beta1.Update,
+			},
+			Rule: admissionregistrationv1beta1.Rule{
+				APIGroups:   []string{es.GroupName},
+				APIVersions: []string{esV1.SchemeGroupVersion.Version, esV2.SchemeGroupVersion.Version},
+				Resources:   []string{esV1.ResourcePlural},
+			},
+		}},
+		FailurePolicy: &failurePolicy,
+	}
+}
+
+// Register creates the validating and mutating webhook configurations of
+// the operator, or updates existing configurations in place
+func Register(clientset kubernetes.Interface, config Config) error {
+	if err := registerValidatingWebhook(clientset, config); err != nil {
+		return err
+	}
+	return registerMutatingWebhook(clientset, config)
+}
+
+func registerValidatingWebhook(clientset kubernetes.Interface, config Config) error {
+	configurations := clientset.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations()
+	webhooks := []admissionregistrationv1beta1.Webhook{
+		config.webhook("validate."+esV1.ResourcePlural+"."+es.GroupName, ValidatePath),
+	}
+
+	configuration, err := configurations.Get(config.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configurations.Create(&admissionregistrationv1beta1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: config.Name},
+			Webhooks:   webhooks,
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	configuration = configuration.DeepCopy()
+	configuration.Webhooks = webhooks
+	_, err = configurations.Update(configuration)
+	return err
+}
+
+func registerMutatingWebhook(clientset kubernetes.Interface, config Config) error {
+	configurations := clientset.AdmissionregistrationV1beta1().MutatingWebhookConfigurations()
+	webhooks := []admissionregistrationv1beta1.Webhook{
+		config.webhook("default."+esV1.ResourcePlural+"."+es.GroupName, MutatePath),
+	}
+
+	configuration, err := configurations.Get(config.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configurations.Create(&admissionregistrationv1beta1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: config.Name},
+			Webhooks:   webhooks,
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	configuration = configuration.DeepCopy()
+	configuration.Webhooks = webhooks
+	_, err = configurations.Update(configuration)
+	return err
+}
+
+// conversionMinorVersion is the first minor version of kubernetes 1 able to
+// call a conversion webhook
+const conversionMinorVersion = 13
+
+// crdVersion is a version of the custom resource definition, with its own
+// schema as the versions differ
+type crdVersion struct {
+	Name    string                     `json:"name"`
+	Served  bool                       `json:"served"`
+	Storage bool                       `json:"storage"`
+	Schema  map[string]json.RawMessage `json:"schema"`
+}
+
+// RegisterConversion serves v2 of the Cluster resource alongside v1, the
+// storage version, converting between them with the conversion webhook.
+// The vendored apiextensions types know neither conversion nor per-version
+// schemas, so the definition is patched after every install; api servers
+// without webhook conversion keep serving v1 only. It returns whether v2 is
+// served.
+func RegisterConversion(clientset apiextensionsclient.Interface, config Config) (bool, error) {
+	info, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		return false, err
+	}
+	minor, err := strconv.Atoi(strings.TrimRight(info.Minor, "+"))
+	if err != nil {
+		return false, fmt.Errorf("unexpected server version %v: %v", info, err)
+	}
+	if info.Major == "1" && minor < conversionMinorVersion {
+		return false, nil
+	}
+
+	v1Schema, err := versionSchema(esV1.ClusterOpenAPISchema)
+	if err != nil {
+		return false, err
+	}
+	v2Schema, err := versionSchema(esV2.ClusterOpenAPISchema)
+	if err != nil {
+		return false, err
+	}
+
+	service := config.clientConfig(ConvertPath).Service
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			// the schemas move to the versions
+			"validation": nil,
+			"versions": []crdVersion{
+				{Name: esV1.SchemeGroupVersion.Version, Served: true, Storage: true, Schema: v1Schema},
+				{Name: esV2.SchemeGroupVersion.Version, Served: true, Schema: v2Schema},
+			},
+			"conversion": map[string]interface{}{
+				"strategy": "Webhook",
+				"webhookClientConfig": map[string]interface{}{
+					"service": map[string]interface{}{
+						"namespace": service.Namespace,
+						"name":      service.Name,
+						"path":      *service.Path,
+					},
+					"caBundle": config.CABundle,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	name := esV1.ResourcePlural + "." + es.GroupName
+	_, err = clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Patch(name, types.MergePatchType, patch)
+	return err == nil, err
+}
+
+// versionSchema returns the schema of a version, whose root must be typed
+// once schemas are given per version
+func versionSchema(schema string) (map[string]json.RawMessage, error) {
+	root := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(schema), &root); err != nil {
+		return nil, fmt.Errorf("invalid Cluster schema: %v", err)
+	}
+	root["type"] = "object"
+	raw, err := json.Marshal(root)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]json.RawMessage{"openAPIV3Schema": raw}, nil
+}
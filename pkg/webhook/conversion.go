@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	esV2 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v2"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ConvertPath is the path of the conversion webhook
+const ConvertPath = "/convert"
+
+// conversionReview is the apiextensions.k8s.io/v1beta1 ConversionReview
+// posted by the api server, which the vendored apiextensions types predate
+type conversionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *conversionRequest  `json:"request,omitempty"`
+	Response        *conversionResponse `json:"response,omitempty"`
+}
+
+type conversionRequest struct {
+	UID               types.UID              `json:"uid"`
+	DesiredAPIVersion string                 `json:"desiredAPIVersion"`
+	Objects           []runtime.RawExtension `json:"objects"`
+}
+
+type conversionResponse struct {
+	UID              types.UID              `json:"uid"`
+	ConvertedObjects []runtime.RawExtension `json:"convertedObjects"`
+	Result           metav1.Status          `json:"result"`
+}
+
+// serveConversion converts the clusters of a conversion review to the
+// desired version. A review fails as a whole when any cluster fails.
+func serveConversion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "expected POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	review := conversionReview{}
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode conversion review: %v", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "conversion review has no request", http.StatusBadRequest)
+		return
+	}
+
+	response := &conversionResponse{
+		UID:    review.Request.UID,
+		Result: metav1.Status{Status: metav1.StatusSuccess},
+	}
+	for _, object := range review.Request.Objects {
+		converted, err := convertCluster(object.Raw, review.Request.DesiredAPIVersion)
+		if err == nil {
+			object.Raw, err = json.Marshal(converted)
+		}
+		if err != nil {
+			response.ConvertedObjects = nil
+			response.Result = metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+			break
+		}
+		response.ConvertedObjects = append(response.ConvertedObjects, runtime.RawExtension{Raw: object.Raw})
+	}
+	review.Request = nil
+	review.Response = response
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		log.NewLogger().Errorf("Failed to write conversion response: %v", err)
+	}
+}
+
+// convertCluster converts a cluster of any served version to the desired
+// version through the v2 hub
+func convertCluster(raw []byte, desiredAPIVersion string) (runtime.Object, error) {
+	hub, err := decodeHub(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch desiredAPIVersion {
+	case esV1.SchemeGroupVersion.String():
+		cluster := &esV1.Cluster{}
+		if err := cluster.ConvertFrom(hub); err != nil {
+			return nil, err
+		}
+		return cluster, nil
+	case esV2.SchemeGroupVersion.String():
+		return hub, nil
+	}
+	return nil, fmt.Errorf("cannot convert cluster %s to unknown version %s", hub.Name, desiredAPIVersion)
+}
+
+// decodeHub decodes a cluster of any served version as the hub version
+func decodeHub(raw []byte) (*esV2.Cluster, error) {
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, fmt.Errorf("failed to decode cluster: %v", err)
+	}
+
+	hub := &esV2.Cluster{}
+	switch typeMeta.APIVersion {
+	case esV1.SchemeGroupVersion.String():
+		cluster := &esV1.Cluster{}
+		if err := json.Unmarshal(raw, cluster); err != nil {
+			return nil, fmt.Errorf("failed to decode cluster: %v", err)
+		}
+		if err := cluster.ConvertTo(hub); err != nil {
+			return nil, err
+		}
+	case esV2.SchemeGroupVersion.String():
+		if err := json.Unmarshal(raw, hub); err != nil {
+			return nil, fmt.Errorf("failed to decode cluster: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("cannot convert cluster of unknown version %s", typeMeta.APIVersion)
+	}
+	return hub, nil
+}
@@ -0,0 +1,178 @@
+// Package webhook serves the admission webhooks validating and defaulting
+// Cluster resources and the webhook converting them between versions, and
+// registers them with the api server.
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	esV2 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v2"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/log"
+	"github.com/matt-tyler/elasticsearch-operator/pkg/validation"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// ValidatePath is the path of the validating webhook
+	ValidatePath = "/validate"
+	// MutatePath is the path of the mutating webhook setting defaults
+	MutatePath = "/mutate"
+)
+
+// admitFunc decides on a single admission request
+type admitFunc func(request *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse
+
+// NewHandler returns the handler serving every webhook
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(ValidatePath, serve(validate))
+	mux.Handle(MutatePath, serve(mutate))
+	mux.HandleFunc(ConvertPath, serveConversion)
+	return mux
+}
+
+// serve decodes the admission review posted by the api server and responds
+// with the decision of admit
+func serve(admit admitFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "expected POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		review := admissionv1beta1.AdmissionReview{}
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			http.Error(w, fmt.Sprintf("failed to decode admission review: %v", err), http.StatusBadRequest)
+			return
+		}
+		if review.Request == nil {
+			http.Error(w, "admission review has no request", http.StatusBadRequest)
+			return
+		}
+
+		response := admit(review.Request)
+		response.UID = review.Request.UID
+		review.Response = response
+		review.Request = nil
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			log.NewLogger().Errorf("Failed to write admission response: %v", err)
+		}
+	})
+}
+
+// decodeCluster decodes a cluster of any served version as a v1 cluster,
+// the version the rules and defaults are written against
+func decodeCluster(raw []byte) (*esV1.Cluster, error) {
+	hub, err := decodeHub(raw)
+	if err != nil {
+		return nil, err
+	}
+	cluster := &esV1.Cluster{}
+	if err := cluster.ConvertFrom(hub); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+func allowed() *admissionv1beta1.AdmissionResponse {
+	return &admissionv1beta1.AdmissionResponse{Allowed: true}
+}
+
+func denied(err error) *admissionv1beta1.AdmissionResponse {
+	status := apierrors.NewBadRequest(err.Error()).Status()
+	if statusErr, ok := err.(apierrors.APIStatus); ok {
+		status = statusErr.Status()
+	}
+	return &admissionv1beta1.AdmissionResponse{Result: &status}
+}
+
+// validate admits clusters passing validation. Updates of a deleted cluster
+// are always admitted, so the controller can remove its finalizer.
+func validate(request *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	cluster, err := decodeCluster(request.Object.Raw)
+	if err != nil {
+		return denied(err)
+	}
+
+	var errs field.ErrorList
+	switch request.Operation {
+	case admissionv1beta1.Create:
+		errs = validation.ValidateCluster(cluster)
+	case admissionv1beta1.Update:
+		if cluster.DeletionTimestamp != nil {
+			return allowed()
+		}
+		old, err := decodeCluster(request.OldObject.Raw)
+		if err != nil {
+			return denied(err)
+		}
+		errs = validation.ValidateClusterUpdate(cluster, old)
+	}
+
+	if len(errs) > 0 {
+		kind := esV1.SchemeGroupVersion.WithKind("Cluster").GroupKind()
+		return denied(apierrors.NewInvalid(kind, cluster.Name, errs))
+	}
+	return allowed()
+}
+
+// patchOperation is a single operation of a json patch
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// mutate sets the defaults of a cluster by replacing its spec
+func mutate(request *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	cluster, err := decodeCluster(request.Object.Raw)
+	if err != nil {
+		return denied(err)
+	}
+	if cluster.DeletionTimestamp != nil {
+		return allowed()
+	}
+
+	defaulted := cluster.DeepCopy()
+	if request.Operation == admissionv1beta1.Update {
+		old, err := decodeCluster(request.OldObject.Raw)
+		if err != nil {
+			return denied(err)
+		}
+		validation.SetClusterUpdateDefaults(defaulted, old)
+	} else {
+		validation.SetClusterDefaults(defaulted)
+	}
+	if apiequality.Semantic.DeepEqual(cluster.Spec, defaulted.Spec) {
+		return allowed()
+	}
+
+	// the patch applies to the version the cluster was written in
+	var spec interface{} = defaulted.Spec
+	if request.Kind.Version == esV2.SchemeGroupVersion.Version {
+		hub := &esV2.Cluster{}
+		if err := defaulted.ConvertTo(hub); err != nil {
+			return denied(err)
+		}
+		spec = hub.Spec
+	}
+
+	// add replaces the spec when present
+	patch, err := json.Marshal([]patchOperation{{Op: "add", Path: "/spec", Value: spec}})
+	if err != nil {
+		return denied(err)
+	}
+	patchType := admissionv1beta1.PatchTypeJSONPatch
+	response := allowed()
+	response.Patch = patch
+	response.PatchType = &patchType
+	return response
+}
@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	esV1 "github.com/matt-tyler/elasticsearch-operator/pkg/apis/es/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newCluster() *esV1.Cluster {
+	cluster := &esV1.Cluster{}
+	cluster.APIVersion = esV1.SchemeGroupVersion.String()
+	cluster.Kind = "Cluster"
+	cluster.Name = "test"
+	cluster.Spec = esV1.ClusterSpec{
+		Name:    "test",
+		Version: "6.4.2",
+		Size:    2,
+	}
+	return cluster
+}
+
+func rawCluster(t *testing.T, cluster *esV1.Cluster) runtime.RawExtension {
+	raw, err := json.Marshal(cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runtime.RawExtension{Raw: raw}
+}
+
+// review posts an admission review of request to the webhook at path and
+// returns the response
+func review(t *testing.T, path string, request *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	request.UID = "request-uid"
+	request.Kind = metav1.GroupVersionKind{Group: esV1.SchemeGroupVersion.Group, Version: esV1.SchemeGroupVersion.Version, Kind: "Cluster"}
+	body, err := json.Marshal(admissionv1beta1.AdmissionReview{Request: request})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	NewHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	response := admissionv1beta1.AdmissionReview{}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Response == nil || response.Response.UID != request.UID {
+		t.Fatalf("expected a response to request %s, got %+v", request.UID, response.Response)
+	}
+	return response.Response
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation admissionv1beta1.Operation
+		update    func(cluster *esV1.Cluster)
+
+		expectedAllowed bool
+		expectedMessage string
+	}{
+		{"create", admissionv1beta1.Create, nil, true, ""},
+		{"create with an invalid version", admissionv1beta1.Create, func(cluster *esV1.Cluster) {
+			cluster.Spec.Version = "6.4"
+		}, false, "spec.version"},
+		{"upgrade", admissionv1beta1.Update, func(cluster *esV1.Cluster) {
+			cluster.Spec.Version = "6.5.0"
+		}, true, ""},
+		{"downgrade", admissionv1beta1.Update, func(cluster *esV1.Cluster) {
+			cluster.Spec.Version = "6.1.1"
+		}, false, "cannot downgrade"},
+		{"downgrade of a deleted cluster", admissionv1beta1.Update, func(cluster *esV1.Cluster) {
+			cluster.Spec.Version = "6.1.1"
+			deleted := metav1.Now()
+			cluster.DeletionTimestamp = &deleted
+		}, true, ""},
+	}
+
+	for _, test := range tests {
+		old := newCluster()
+		cluster := old.DeepCopy()
+		if test.update != nil {
+			test.update(cluster)
+		}
+		request := &admissionv1beta1.AdmissionRequest{Operation: test.operation, Object: rawCluster(t, cluster)}
+		if test.operation == admissionv1beta1.Update {
+			request.OldObject = rawCluster(t, old)
+		}
+
+		response := review(t, ValidatePath, request)
+		if response.Allowed != test.expectedAllowed {
+			t.Errorf("%s: expected allowed %v, got %+v", test.name, test.expectedAllowed, response.Result)
+			continue
+		}
+		if !test.expectedAllowed && (response.Result == nil || !strings.Contains(response.Result.Message, test.expectedMessage)) {
+			t.Errorf("%s: expected denial mentioning %q, got %+v", test.name, test.expectedMessage, response.Result)
+		}
+	}
+}
+
+// patchedSpec returns the spec set by the json patch of a response
+func patchedSpec(t *testing.T, response *admissionv1beta1.AdmissionResponse) *esV1.ClusterSpec {
+	if response.PatchType == nil || *response.PatchType != admissionv1beta1.PatchTypeJSONPatch {
+		t.Fatalf("expected a json patch, got %v", response.PatchType)
+	}
+	var patch []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(response.Patch, &patch); err != nil {
+		t.Fatal(err)
+	}
+	if len(patch) != 1 || patch[0].Path != "/spec" {
+		t.Fatalf("expected the spec replaced, got %s", response.Patch)
+	}
+	spec := &esV1.ClusterSpec{}
+	if err := json.Unmarshal(patch[0].Value, spec); err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestMutateSetsDefaults(t *testing.T) {
+	cluster := newCluster()
+	cluster.Spec.Version = ""
+	cluster.Spec.NodePools = []esV1.NodePool{{
+		Name:  "master",
+		Roles: []esV1.NodeRole{esV1.NodeRoleMaster, esV1.NodeRoleData},
+		Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")},
+		},
+	}}
+
+	response := review(t, MutatePath, &admissionv1beta1.AdmissionRequest{
+		Operation: admissionv1beta1.Create,
+		Object:    rawCluster(t, cluster),
+	})
+	if !response.Allowed {
+		t.Fatalf("expected cluster allowed, got %+v", response.Result)
+	}
+
+	spec := patchedSpec(t, response)
+	if spec.Version != esV1.DefaultVersion {
+		t.Errorf("expected version %s, got %s", esV1.DefaultVersion, spec.Version)
+	}
+	if heap := spec.NodePools[0].Heap; heap == nil || heap.String() != "2Gi" {
+		t.Errorf("expected heap of half the memory limit, got %v", heap)
+	}
+}
+
+func TestMutateDefaultedCluster(t *testing.T) {
+	cluster := newCluster()
+	cluster.Spec.DeletionPolicy = esV1.DeletionPolicyDelete
+	cluster.Spec.Storage = esV1.StorageSpec{
+		Size:        resource.MustParse(esV1.DefaultStorageSize),
+		AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+	}
+
+	response := review(t, MutatePath, &admissionv1beta1.AdmissionRequest{
+		Operation: admissionv1beta1.Create,
+		Object:    rawCluster(t, cluster),
+	})
+	if !response.Allowed || response.Patch != nil {
+		t.Errorf("expected a defaulted cluster allowed unchanged, got patch %s", response.Patch)
+	}
+}